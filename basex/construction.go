@@ -6,30 +6,36 @@ import (
 	"math"
 )
 
-// SetAlphabet
+// SetAlphabet. On error the transcoder keeps its previous alphabet.
 func (bx *BaseX) SetAlphabet(alphabet string) error {
     if len(alphabet) < 2 {
         return errors.New("Alphabet must be >= 2 chars")
     }
-    bx.alphabet = alphabet
-    bx.base = len(alphabet)
-    bx.leader = alphabet[0]
+    if len(alphabet) >= 255 {
+        return errors.New("alphabet too long")
+    }
+    next := BaseX{
+        alphabet: alphabet,
+        base:     len(alphabet),
+        leader:   alphabet[0],
+    }
 
     // BaseMap initialisieren
-    for i := range bx.baseMap {
-        bx.baseMap[i] = 255
+    for i := range next.baseMap {
+        next.baseMap[i] = 255
     }
-    for i := 0; i < bx.base; i++ {
+    for i := 0; i < next.base; i++ {
         c := alphabet[i]
-        if bx.baseMap[c] != 255 {
+        if next.baseMap[c] != 255 {
             return fmt.Errorf("%q is ambiguous in alphabet", c)
         }
-        bx.baseMap[c] = uint8(i)
+        next.baseMap[c] = uint8(i)
     }
 
     // Log-Faktoren
-    bx.factor = math.Log(float64(bx.base)) / math.Log(256)
-    bx.iFactor = math.Log(256) / math.Log(float64(bx.base))
+    next.factor = math.Log(float64(next.base)) / math.Log(256)
+    next.iFactor = math.Log(256) / math.Log(float64(next.base))
+    *bx = next
     return nil
 }
 
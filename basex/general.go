@@ -8,6 +8,9 @@ const (
     defaultAlphabet string      = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
 )
 
+// Returned by Decode for characters that are not part of the alphabet
+var ErrInvalidChar = errors.New("invalid char")
+
 // BaseX entspricht grob deiner C++-Klasse.
 type BaseX struct {
     alphabet string
@@ -18,11 +21,9 @@ type BaseX struct {
     iFactor  float64 // log(256) / log(Base)
 }
 
-// Construct a new BaseX transcoder with a custom alphabet
+// Construct a new BaseX transcoder with a custom alphabet.
+// A BaseX is safe for concurrent Encode/Decode as long as SetAlphabet is not called.
 func NewBaseX(alphabet string) (*BaseX, error) {
-    if len(alphabet) >= 255 {
-        return nil, errors.New("alphabet too long")
-    }
     bx := &BaseX{}
     if err := bx.SetAlphabet(alphabet); err != nil {
         return nil, err
@@ -78,7 +78,7 @@ func (bx *BaseX) Decode(str string) ([]byte, error) {
         c := str[idx]
         carry := uint32(bx.baseMap[c])
         if carry == 255 {
-            return nil, fmt.Errorf("invalid char %q for base %d", c, bx.base)
+            return nil, fmt.Errorf("%w %q for base %d", ErrInvalidChar, c, bx.base)
         }
         i := 0
         for it := size - 1; (carry != 0 || i < length) && it >= 0; it, i = it-1, i+1 {
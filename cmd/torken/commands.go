@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/thelaumix/go-torken/tokenizer"
+)
+
+func cmdEncrypt(args []string, stdin io.Reader, stdout io.Writer) error {
+    fs := flag.NewFlagSet("encrypt", flag.ContinueOnError)
+    var c commonFlags
+    c.register(fs, true)
+    in := fs.String("in", "", "file with the JSON payload (default: stdin)")
+    id := fs.String("id", "", `identifier as 24 hex chars, "random", or empty for an anonymous token`)
+    algo := fs.String("algo", "chacha20", "algorithm: chacha20, aes, xchacha20")
+    expires := fs.Duration("expires", 0, "lifetime, e.g. 15m or 24h, rounded up to whole seconds (default: never expires)")
+    validFrom := fs.String("valid-from", "", "issue time as RFC 3339 (default: now)")
+    version := fs.Uint("version", 0, "token version (default: latest)")
+    kdf := fs.String("kdf", "none", "key derivation: none, hkdf, scrypt, argon2id")
+    tagLength := fs.Int("tag-length", 0, "MAC tag length in bytes, multiple of 4 in 8..32 (default: 16)")
+    singleUse := fs.Bool("single-use", false, "mark the token single-use")
+    purpose := fs.String("purpose", "", "bind the token to a purpose, required again for decryption")
+    aad := fs.String("aad", "", "bind the token to associated data, required again for decryption")
+    fingerprint := fs.String("fingerprint", "", "bind the token to a client fingerprint, required again for decryption")
+    if err := fs.Parse(args); err != nil {
+        return exitError{2, err}
+    }
+
+    key, err := c.resolveKey()
+    if err != nil {
+        return err
+    }
+    t, err := c.tokenizer()
+    if err != nil {
+        return err
+    }
+
+    var raw []byte
+    if len(*in) > 0 {
+        raw, err = os.ReadFile(*in)
+    } else {
+        raw, err = io.ReadAll(stdin)
+    }
+    if err != nil {
+        return err
+    }
+    payload, err := parsePayload(raw)
+    if err != nil {
+        return fmt.Errorf("payload is not valid JSON: %w", err)
+    }
+
+    opts := tokenizer.EncryptOptions()
+    a, err := parseAlgorithm(*algo)
+    if err != nil {
+        return err
+    }
+    opts.Algorithm(a)
+    if *expires != 0 {
+        // aufgerundet wie TokenKind.TTL, 0 wäre nie ablaufend
+        if *expires < 0 || (*expires+time.Second-1)/time.Second > math.MaxUint32 {
+            return fmt.Errorf("-expires has to be between 1s and %ds", uint32(math.MaxUint32))
+        }
+        opts.ExpiresIn(uint32((*expires + time.Second - 1) / time.Second))
+    }
+    if len(*validFrom) > 0 {
+        ts, err := time.Parse(time.RFC3339, *validFrom)
+        if err != nil {
+            return fmt.Errorf("invalid -valid-from: %w", err)
+        }
+        opts.ValidFrom(ts)
+    }
+    if *version > math.MaxUint8 {
+        return fmt.Errorf("invalid -version %d", *version)
+    }
+    if *version > 0 {
+        opts.Version(uint8(*version))
+    }
+    k, err := parseKDF(*kdf)
+    if err != nil {
+        return err
+    }
+    if k != nil {
+        opts.KDF(*k)
+    }
+    if *tagLength > 0 {
+        opts.TagLength(*tagLength)
+    }
+    if *singleUse {
+        opts.SingleUse()
+    }
+    opts.Purpose(*purpose)
+    if len(*aad) > 0 {
+        opts.AssociatedData([]byte(*aad))
+    }
+    if len(*fingerprint) > 0 {
+        opts.Fingerprint([]byte(*fingerprint))
+    }
+
+    var identifier *tokenizer.Identifier
+    switch *id {
+    case "":
+        identifier = tokenizer.NewIdentifierAnonymous()
+    case "random":
+        identifier = tokenizer.NewIdentifier()
+    default:
+        identifier, err = tokenizer.NewIdentifierFromHex(*id)
+        if err != nil {
+            return fmt.Errorf("invalid -id: %w", err)
+        }
+    }
+
+    token, err := t.Encrypt(payload, key, identifier, opts)
+    if err != nil {
+        return err
+    }
+    _, err = fmt.Fprintln(stdout, token)
+    return err
+}
+
+// JSON payload with integers kept as int64 / uint64, so large IDs keep their precision
+func parsePayload(raw []byte) (any, error) {
+    dec := json.NewDecoder(bytes.NewReader(raw))
+    dec.UseNumber()
+    var payload any
+    if err := dec.Decode(&payload); err != nil {
+        return nil, err
+    }
+    if _, err := dec.Token(); err != io.EOF {
+        return nil, errors.New("trailing data after the payload")
+    }
+    return convertNumbers(payload)
+}
+
+func convertNumbers(v any) (any, error) {
+    switch v := v.(type) {
+    case json.Number:
+        if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+            return i, nil
+        }
+        if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+            return u, nil
+        }
+        return v.Float64()
+    case map[string]any:
+        for k, e := range v {
+            c, err := convertNumbers(e)
+            if err != nil {
+                return nil, err
+            }
+            v[k] = c
+        }
+    case []any:
+        for i, e := range v {
+            c, err := convertNumbers(e)
+            if err != nil {
+                return nil, err
+            }
+            v[i] = c
+        }
+    }
+    return v, nil
+}
+
+// JSON output of decrypt and inspect
+type tokenInfo struct {
+    Version    uint8      `json:"version"`
+    Algorithm  string     `json:"algorithm"`
+    Identifier string     `json:"identifier,omitempty"`
+    ValidFrom  time.Time  `json:"validFrom"`
+    ExpiresIn  uint32     `json:"expiresIn"`
+    ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+    Valid      bool       `json:"valid"`
+    Invalid    string     `json:"invalid,omitempty"`
+    KeyID      string     `json:"keyId,omitempty"`
+    KDF        string     `json:"kdf,omitempty"`
+    TagLength  int        `json:"tagLength,omitempty"`
+    Origin     *time.Time `json:"origin,omitempty"`
+    SingleUse  bool       `json:"singleUse,omitempty"`
+    FingerprintBound bool `json:"fingerprintBound,omitempty"`
+    Payload    any        `json:"payload,omitempty"`
+}
+
+func (i *tokenInfo) setValidity(validFrom time.Time, expiresIn uint32, err error) {
+    i.ValidFrom = validFrom.UTC()
+    i.ExpiresIn = expiresIn
+    if expiresIn > 0 {
+        at := i.ValidFrom.Add(time.Duration(expiresIn) * time.Second)
+        i.ExpiresAt = &at
+    }
+    i.Valid = err == nil
+    if err != nil {
+        i.Invalid = err.Error()
+    }
+}
+
+func writeJSON(w io.Writer, v any) error {
+    enc := json.NewEncoder(w)
+    enc.SetIndent("", "  ")
+    return enc.Encode(v)
+}
+
+func cmdDecrypt(args []string, stdin io.Reader, stdout io.Writer) error {
+    fs := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+    var c commonFlags
+    c.register(fs, true)
+    strict := fs.Bool("strict", false, "fail for tokens outside their validity period")
+    payloadOnly := fs.Bool("payload", false, "print only the payload")
+    purpose := fs.String("purpose", "", "purpose the token has been encrypted for")
+    aad := fs.String("aad", "", "associated data the token has been encrypted with")
+    fingerprint := fs.String("fingerprint", "", "client fingerprint of a bound token")
+    kdf := fs.String("kdf", "", "key derivation the token uses: none, hkdf, scrypt, argon2id (default: taken from the token, hkdf only)")
+    if err := fs.Parse(args); err != nil {
+        return exitError{2, err}
+    }
+
+    key, err := c.resolveKey()
+    if err != nil {
+        return err
+    }
+    t, err := c.tokenizer()
+    if err != nil {
+        return err
+    }
+    token, err := readToken(fs, stdin)
+    if err != nil {
+        return err
+    }
+
+    dopts := tokenizer.DecryptOptions().Strict(*strict).Purpose(*purpose)
+    if len(*aad) > 0 {
+        dopts.AssociatedData([]byte(*aad))
+    }
+    if len(*fingerprint) > 0 {
+        dopts.Fingerprint([]byte(*fingerprint))
+    }
+    if len(*kdf) > 0 {
+        k, err := parseKDF(*kdf)
+        if err != nil {
+            return err
+        }
+        if k == nil {
+            dopts.KDF(tokenizer.KDF{})
+        } else {
+            dopts.KDF(*k)
+        }
+    }
+    var payload any
+    r, err := t.Decrypt(token, key, dopts).Into(&payload)
+    if err != nil {
+        return err
+    }
+    if *payloadOnly {
+        return writeJSON(stdout, payload)
+    }
+
+    info := tokenInfo{
+        Version:   r.Version(),
+        Algorithm: algorithmName(r.Algorithm()),
+        Payload:   payload,
+    }
+    id := r.Identifier()
+    info.Identifier = id.Hex()
+    if kid, ok := r.KeyID(); ok {
+        info.KeyID = hex.EncodeToString(kid[:])
+    }
+    info.setValidity(r.ValidFrom(), r.ExpiresIn(), r.Err())
+    return writeJSON(stdout, info)
+}
+
+func cmdInspect(args []string, stdin io.Reader, stdout io.Writer) error {
+    fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+    var c commonFlags
+    c.register(fs, false)
+    if err := fs.Parse(args); err != nil {
+        return exitError{2, err}
+    }
+
+    t, err := c.tokenizer()
+    if err != nil {
+        return err
+    }
+    token, err := readToken(fs, stdin)
+    if err != nil {
+        return err
+    }
+
+    h, err := t.Inspect(token, nil)
+    if err != nil {
+        return err
+    }
+    info := tokenInfo{
+        Version:   h.Version(),
+        Algorithm: algorithmName(h.Algorithm()),
+        KDF:       kdfName(h.KDF()),
+        TagLength: h.TagLength(),
+        SingleUse: h.SingleUse(),
+        FingerprintBound: h.FingerprintBound(),
+    }
+    id := h.Identifier()
+    info.Identifier = id.Hex()
+    if kid, ok := h.KeyID(); ok {
+        info.KeyID = hex.EncodeToString(kid[:])
+    }
+    if origin := h.Origin().UTC(); !origin.Equal(h.ValidFrom()) {
+        info.Origin = &origin
+    }
+    info.setValidity(h.ValidFrom(), h.ExpiresIn(), h.Err())
+    return writeJSON(stdout, info)
+}
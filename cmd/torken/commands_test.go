@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func encrypt(t *testing.T, args ...string) (string, error) {
+    t.Helper()
+    var out bytes.Buffer
+    err := cmdEncrypt(append([]string{"-key", "secret"}, args...), strings.NewReader(`{"a":1}`), &out)
+    return strings.TrimSpace(out.String()), err
+}
+
+func TestEncryptRejectsBadExpires(t *testing.T) {
+    for _, v := range []string{"-1s", "1193047h"} {
+        if _, err := encrypt(t, "-expires", v); err == nil {
+            t.Errorf("-expires %s: no error", v)
+        }
+    }
+}
+
+// Rounded up like TokenKind.TTL
+func TestEncryptExpiresRoundsUp(t *testing.T) {
+    for v, want := range map[string]string{"1ns": `"expiresIn": 1,`, "1s": `"expiresIn": 1,`, "1500ms": `"expiresIn": 2,`} {
+        token, err := encrypt(t, "-expires", v)
+        if err != nil {
+            t.Fatalf("-expires %s: %v", v, err)
+        }
+        var out bytes.Buffer
+        if err := cmdInspect([]string{token}, nil, &out); err != nil || !strings.Contains(out.String(), want) {
+            t.Errorf("-expires %s: %v %s", v, err, out.String())
+        }
+    }
+}
+
+// Integers keep their precision instead of going through float64
+func TestEncryptKeepsIntegers(t *testing.T) {
+    payload := `{"n":9007199254740993,"neg":-3,"u":18446744073709551615,"f":1.5,"list":[1,2.5]}`
+    var token bytes.Buffer
+    if err := cmdEncrypt([]string{"-key", "secret"}, strings.NewReader(payload), &token); err != nil {
+        t.Fatal(err)
+    }
+    var out bytes.Buffer
+    if err := cmdDecrypt([]string{"-key", "secret", "-payload"}, &token, &out); err != nil {
+        t.Fatal(err)
+    }
+    var got, want any
+    json.Unmarshal(out.Bytes(), &got)
+    json.Unmarshal([]byte(payload), &want)
+    if compact := strings.Join(strings.Fields(out.String()), ""); !strings.Contains(compact, `"n":9007199254740993`) || !strings.Contains(compact, `"u":18446744073709551615`) || !reflect.DeepEqual(got, want) {
+        t.Errorf("got %s", out.String())
+    }
+
+    if err := cmdEncrypt([]string{"-key", "secret"}, strings.NewReader(`{"a":1} {}`), &token); err == nil {
+        t.Error("trailing data: no error")
+    }
+}
+
+// The CLI has a replay cache of its own, so a single-use token can be looked at once
+func TestDecryptSingleUse(t *testing.T) {
+    token, err := encrypt(t, "-id", "random", "-expires", "1h", "-single-use")
+    if err != nil {
+        t.Fatal(err)
+    }
+    var out bytes.Buffer
+    if err := cmdDecrypt([]string{"-key", "secret", token}, nil, &out); err != nil || !strings.Contains(out.String(), `"a": 1`) {
+        t.Errorf("single-use: %v %s", err, out.String())
+    }
+}
+
+func TestEncryptRejectsBadVersion(t *testing.T) {
+    for _, v := range []string{"260", "5"} {
+        if _, err := encrypt(t, "-version", v); err == nil {
+            t.Errorf("-version %s: no error", v)
+        }
+    }
+    if _, err := encrypt(t, "-version", "4"); err != nil {
+        t.Errorf("-version 4: %v", err)
+    }
+}
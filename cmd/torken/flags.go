@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/thelaumix/go-torken/tokenizer"
+)
+
+// Flags shared by encrypt, decrypt and inspect
+type commonFlags struct {
+    key       string
+    keyFile   string
+    scrambler string
+    alphabet  string
+    kdfSalt   string
+}
+
+func (c *commonFlags) register(fs *flag.FlagSet, withKey bool) {
+    if withKey {
+        fs.StringVar(&c.key, "key", "", "key (visible in the process list, prefer -key-file or TORKEN_KEY)")
+        fs.StringVar(&c.keyFile, "key-file", "", "file containing the key")
+        fs.StringVar(&c.kdfSalt, "kdf-salt", "", "salt of the key derivation (default: library default)")
+    }
+    fs.StringVar(&c.scrambler, "scrambler", "", "scrambler (default: library default)")
+    fs.StringVar(&c.alphabet, "alphabet", "", "baseX alphabet (default: library default)")
+}
+
+// Key from -key, -key-file or TORKEN_KEY
+func (c *commonFlags) resolveKey() (string, error) {
+    switch {
+    case len(c.key) > 0:
+        return c.key, nil
+    case len(c.keyFile) > 0:
+        b, err := os.ReadFile(c.keyFile)
+        if err != nil {
+            return "", err
+        }
+        return strings.TrimRight(string(b), "\r\n"), nil
+    case len(os.Getenv("TORKEN_KEY")) > 0:
+        return os.Getenv("TORKEN_KEY"), nil
+    }
+    return "", errors.New("no key given (-key, -key-file or TORKEN_KEY)")
+}
+
+// Tokenizer with a replay cache of its own, so single-use tokens can be decrypted once per run
+func (c *commonFlags) tokenizer() (*tokenizer.Tokenizer, error) {
+    opts := tokenizer.TokenizerOptions().ReplayCache(tokenizer.NewMemoryReplayCache(nil))
+    if len(c.scrambler) > 0 {
+        opts.Scrambler(c.scrambler)
+    }
+    if len(c.alphabet) > 0 {
+        opts.Alphabet(c.alphabet)
+    }
+    if len(c.kdfSalt) > 0 {
+        opts.KDFSalt([]byte(c.kdfSalt))
+    }
+    return tokenizer.NewTokenizerWith(opts)
+}
+
+// Token from the first argument or the first line of stdin
+func readToken(fs *flag.FlagSet, stdin io.Reader) (string, error) {
+    if fs.NArg() > 1 {
+        return "", errors.New("too many arguments")
+    }
+    if fs.NArg() == 1 {
+        return strings.TrimSpace(fs.Arg(0)), nil
+    }
+    line, err := bufio.NewReader(stdin).ReadString('\n')
+    if err != nil && !errors.Is(err, io.EOF) {
+        return "", err
+    }
+    token := strings.TrimSpace(line)
+    if len(token) == 0 {
+        return "", errors.New("no token given")
+    }
+    return token, nil
+}
+
+func parseAlgorithm(s string) (tokenizer.TAlgorithm, error) {
+    switch strings.ToLower(s) {
+    case "chacha20":
+        return tokenizer.TALGO_CHACHA20, nil
+    case "aes":
+        return tokenizer.TALGO_AES, nil
+    case "xchacha20":
+        return tokenizer.TALGO_XCHACHA20, nil
+    }
+    return 0, fmt.Errorf("unknown algorithm %q (chacha20, aes, xchacha20)", s)
+}
+
+func algorithmName(a tokenizer.TAlgorithm) string {
+    switch a {
+    case tokenizer.TALGO_CHACHA20:
+        return "chacha20"
+    case tokenizer.TALGO_AES:
+        return "aes"
+    case tokenizer.TALGO_XCHACHA20:
+        return "xchacha20"
+    }
+    return fmt.Sprintf("unknown(%d)", a)
+}
+
+func parseKDF(s string) (*tokenizer.KDF, error) {
+    var k tokenizer.KDF
+    switch strings.ToLower(s) {
+    case "", "none":
+        return nil, nil
+    case "hkdf":
+        k = tokenizer.HKDF()
+    case "scrypt":
+        k = tokenizer.ScryptDefault()
+    case "argon2id":
+        k = tokenizer.Argon2idDefault()
+    default:
+        return nil, fmt.Errorf("unknown key derivation %q (none, hkdf, scrypt, argon2id)", s)
+    }
+    return &k, nil
+}
+
+func kdfName(k tokenizer.KDF) string {
+    switch k.Algorithm {
+    case tokenizer.KDF_NONE:
+        return "none"
+    case tokenizer.KDF_HKDF:
+        return "hkdf"
+    case tokenizer.KDF_SCRYPT:
+        return fmt.Sprintf("scrypt(logN=%d,r=%d,p=%d)", k.P1, k.P2, k.P3)
+    case tokenizer.KDF_ARGON2ID:
+        return fmt.Sprintf("argon2id(t=%d,m=2^%d KiB,p=%d)", k.P1, k.P2, k.P3)
+    }
+    return fmt.Sprintf("unknown(%d)", k.Algorithm)
+}
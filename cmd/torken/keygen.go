@@ -0,0 +1,87 @@
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/thelaumix/go-torken/basex"
+)
+
+const keyCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+func cmdKeygen(args []string, stdout io.Writer) error {
+    fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+    typ := fs.String("type", "key", "what to generate: key, scrambler, alphabet")
+    length := fs.Int("length", 32, "length of keys and scramblers")
+    charset := fs.String("charset", "", "characters of a generated alphabet (default: the library alphabet)")
+    count := fs.Int("n", 1, "number of values")
+    if err := fs.Parse(args); err != nil {
+        return exitError{2, err}
+    }
+    if *length < 1 || *count < 1 {
+        return fmt.Errorf("-length and -n have to be positive")
+    }
+
+    for i := 0; i < *count; i++ {
+        var out string
+        var err error
+        switch *typ {
+        case "key", "scrambler":
+            out, err = randomString(keyCharset, *length)
+        case "alphabet":
+            set := *charset
+            if len(set) == 0 {
+                set = basex.NewBaseXDefault().GetAlphabet()
+            }
+            if _, err := basex.NewBaseX(set); err != nil {
+                return fmt.Errorf("invalid -charset: %w", err)
+            }
+            out, err = shuffle(set)
+        default:
+            return fmt.Errorf("unknown -type %q (key, scrambler, alphabet)", *typ)
+        }
+        if err != nil {
+            return err
+        }
+        if _, err := fmt.Fprintln(stdout, out); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func randomIndex(n int) (int, error) {
+    v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+    if err != nil {
+        return 0, err
+    }
+    return int(v.Int64()), nil
+}
+
+func randomString(charset string, length int) (string, error) {
+    out := make([]byte, length)
+    for i := range out {
+        j, err := randomIndex(len(charset))
+        if err != nil {
+            return "", err
+        }
+        out[i] = charset[j]
+    }
+    return string(out), nil
+}
+
+// Fisher-Yates mit crypto/rand
+func shuffle(s string) (string, error) {
+    out := []byte(s)
+    for i := len(out) - 1; i > 0; i-- {
+        j, err := randomIndex(i + 1)
+        if err != nil {
+            return "", err
+        }
+        out[i], out[j] = out[j], out[i]
+    }
+    return string(out), nil
+}
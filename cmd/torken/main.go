@@ -0,0 +1,69 @@
+// Command torken issues, decrypts and inspects torken tokens.
+//
+//   echo '{"user":"alice"}' | torken encrypt -key-file key.txt -expires 1h > token.txt
+//   torken decrypt -key-file key.txt < token.txt
+//   torken inspect < token.txt
+//   torken keygen -type key
+//
+// Keys are read from -key, -key-file or the TORKEN_KEY environment variable.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `usage: torken <command> [flags]
+
+commands:
+  encrypt   JSON payload (stdin or -in) to token
+  decrypt   token (argument or stdin) to JSON payload and metadata
+  inspect   keyless header dump of a token
+  keygen    random keys, scramblers and alphabets
+
+Run "torken <command> -h" for the flags of a command.
+`
+
+func main() {
+    if len(os.Args) < 2 {
+        fmt.Fprint(os.Stderr, usage)
+        os.Exit(2)
+    }
+
+    var err error
+    args := os.Args[2:]
+    switch os.Args[1] {
+    case "encrypt":
+        err = cmdEncrypt(args, os.Stdin, os.Stdout)
+    case "decrypt":
+        err = cmdDecrypt(args, os.Stdin, os.Stdout)
+    case "inspect":
+        err = cmdInspect(args, os.Stdin, os.Stdout)
+    case "keygen":
+        err = cmdKeygen(args, os.Stdout)
+    case "help", "-h", "-help", "--help":
+        fmt.Fprint(os.Stdout, usage)
+        return
+    default:
+        fmt.Fprintf(os.Stderr, "torken: unknown command %q\n\n%s", os.Args[1], usage)
+        os.Exit(2)
+    }
+
+    if err != nil {
+        fmt.Fprintln(os.Stderr, "torken:", err)
+        if e, ok := err.(exitError); ok {
+            os.Exit(e.code)
+        }
+        os.Exit(1)
+    }
+}
+
+// Error with a specific exit code
+type exitError struct {
+    code int
+    err  error
+}
+
+func (e exitError) Error() string { return e.err.Error() }
+func (e exitError) Unwrap() error { return e.err }
+
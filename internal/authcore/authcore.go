@@ -0,0 +1,130 @@
+// Package authcore holds what torkenhttp and torkengrpc share: the decrypt
+// options built from the middleware settings, the key resolution, the context
+// entry and the error classification. R is the per-call source, *http.Request
+// or the call context.
+package authcore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/thelaumix/go-torken/tokenizer"
+)
+
+type Options[R any] struct {
+    Resolver    func(r R, id tokenizer.Identifier) (string, error)
+    Ring        *tokenizer.KeyRing
+    Scrambler   *string
+    Alphabet    *string
+    Leeway      time.Duration
+    Lenient     bool
+    KDF         *tokenizer.KDF
+    Scopes      []string
+    Purpose     string
+    AAD         func(r R) []byte
+    Fingerprint func(r R) []byte
+}
+
+// Either a key resolver or a key ring is configured
+func (o *Options[R]) HasKey() bool {
+    return o.Resolver != nil || o.Ring != nil
+}
+
+// Decrypts token into claims of type T. An error of the key resolver is
+// returned as is, so it is not mistaken for a rejected token.
+func Authenticate[T any, R any](t *tokenizer.Tokenizer, o *Options[R], r R, token string) (T, *tokenizer.Result, error) {
+    var claims T
+
+    dopts := tokenizer.DecryptOptions().Strict(!o.Lenient).Leeway(o.Leeway).RequireScopes(o.Scopes...).Purpose(o.Purpose)
+    if o.Scrambler != nil {
+        dopts.Scrambler(*o.Scrambler)
+    }
+    if o.Alphabet != nil {
+        dopts.Alphabet(*o.Alphabet)
+    }
+    if o.KDF != nil {
+        dopts.KDF(*o.KDF)
+    }
+    if o.AAD != nil {
+        dopts.AssociatedData(o.AAD(r))
+    }
+    if o.Fingerprint != nil {
+        dopts.Fingerprint(o.Fingerprint(r))
+    }
+
+    if o.Ring != nil {
+        res, err := t.DecryptRing(token, o.Ring, dopts).Into(&claims)
+        return claims, res, err
+    }
+
+    var resolveErr error
+    res, err := t.DecryptFn(token, func(id tokenizer.Identifier) string {
+        key, err := o.Resolver(r, id)
+        if err != nil {
+            resolveErr = err
+            return ""
+        }
+        return key
+    }, dopts).Into(&claims)
+    if resolveErr != nil {
+        return claims, nil, resolveErr
+    }
+    return claims, res, err
+}
+
+type ctxKey struct{}
+
+type ctxEntry struct {
+    claims any
+    result *tokenizer.Result
+}
+
+// Context carrying the claims and the result of an authenticated token
+func WithClaims(ctx context.Context, claims any, res *tokenizer.Result) context.Context {
+    return context.WithValue(ctx, ctxKey{}, &ctxEntry{claims: claims, result: res})
+}
+
+// Claims stored by WithClaims, false if there are none of type T
+func Claims[T any](ctx context.Context) (T, bool) {
+    e, ok := ctx.Value(ctxKey{}).(*ctxEntry)
+    if !ok {
+        var zero T
+        return zero, false
+    }
+    v, ok := e.claims.(T)
+    return v, ok
+}
+
+// Result stored by WithClaims
+func Result(ctx context.Context) (*tokenizer.Result, bool) {
+    e, ok := ctx.Value(ctxKey{}).(*ctxEntry)
+    if !ok {
+        return nil, false
+    }
+    return e.result, true
+}
+
+// How an authentication error is reported to the client
+type Class uint8
+
+const (
+    CLASS_INTERNAL        Class = iota // e.g. a failing key resolver
+    CLASS_UNAUTHENTICATED              // missing or rejected token
+    CLASS_FORBIDDEN                    // valid token lacking a required scope
+)
+
+// Class of an authentication error. noToken is the ErrNoToken of the calling package.
+func Classify(err, noToken error) Class {
+    var te *tokenizer.TokenError
+    switch {
+    case errors.Is(err, tokenizer.ErrInsufficientScope):
+        return CLASS_FORBIDDEN
+    case errors.Is(err, noToken),
+        errors.Is(err, tokenizer.ErrUnknownKey),
+        errors.Is(err, tokenizer.ErrKeyRetired),
+        errors.As(err, &te):
+        return CLASS_UNAUTHENTICATED
+    }
+    return CLASS_INTERNAL
+}
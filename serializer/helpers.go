@@ -3,12 +3,97 @@ package serializer
 import (
 	"fmt"
 	"math/big"
+	"reflect"
+	"sync"
 )
 
 func er(format string, a ...any) (error) {
     return fmt.Errorf(format, a...)
 }
 
+// Struct field with a torken tag, possibly promoted from an embedded struct
+type torkenField struct {
+    name  string
+    index []int
+}
+
+// reflect.Type => []torkenField
+var torkenFieldCache sync.Map
+
+// Tagged fields of a struct type. Exported embedded structs without a tag are
+// flattened into the outer object; on name clashes the shallower field wins,
+// clashes at the same depth drop the field. Embedded struct pointers without a tag are not supported.
+func torkenFields(typ reflect.Type) ([]torkenField, error) {
+    if cached, ok := torkenFieldCache.Load(typ); ok {
+        return cached.([]torkenField), nil
+    }
+    fields, err := collectTorkenFields(typ)
+    if err != nil {
+        return nil, err
+    }
+    torkenFieldCache.Store(typ, fields)
+    return fields, nil
+}
+
+// Fields by tag, breadth first through untagged embedded structs like encoding/json:
+// the shallowest field wins, a tag found in several embedded structs at the same
+// depth is ambiguous and dropped (hiding deeper ones as well). Within one struct
+// the first field with a tag wins.
+func collectTorkenFields(typ reflect.Type) ([]torkenField, error) {
+    var fields []torkenField
+    seen := make(map[string]bool)
+
+    type level struct {
+        typ   reflect.Type
+        index []int
+    }
+    current := []level{{typ, nil}}
+    for len(current) > 0 {
+        var next []level
+        var candidates []torkenField
+        count := make(map[string]int)
+        for _, l := range current {
+            local := make(map[string]bool)
+            for i := 0; i < l.typ.NumField(); i++ {
+                sf := l.typ.Field(i)
+                // only use exported fields
+                if sf.PkgPath != "" {
+                    continue
+                }
+                index := append(append([]int(nil), l.index...), i)
+                tag, ok := sf.Tag.Lookup("torken")
+                if ok && tag != "" {
+                    if !seen[tag] && !local[tag] {
+                        local[tag] = true
+                        count[tag]++
+                        candidates = append(candidates, torkenField{name: tag, index: index})
+                    }
+                    continue
+                }
+                if !sf.Anonymous {
+                    continue
+                }
+                switch {
+                case sf.Type.Kind() == reflect.Struct:
+                    next = append(next, level{sf.Type, index})
+                case sf.Type.Kind() == reflect.Ptr && sf.Type.Elem().Kind() == reflect.Struct:
+                    return nil, er("Embedded struct pointer %s needs a torken tag", sf.Name)
+                }
+            }
+        }
+        for _, f := range candidates {
+            if count[f.name] == 1 {
+                fields = append(fields, f)
+            }
+        }
+        for name := range count {
+            seen[name] = true
+        }
+        current = next
+    }
+    return fields, nil
+}
+
 
 func bigintToBuffer(bigint *big.Int) []byte {
     var (
@@ -22,6 +22,11 @@ func setControlled(out *reflect.Value, use any, useWriting bool) error {
     if !out.CanSet() {
         return er("Out target is not settable")
     }
+    // Null
+    if use == nil {
+        out.Set(reflect.Zero(out.Type()))
+        return nil
+    }
     out.Set(reflect.ValueOf(use))
     return nil
 }
@@ -57,32 +62,40 @@ func df_readArray(r *bytes.Reader, val *reflect.Value, typkind reflect.Kind, use
 
     var (
         slice reflect.Value    = *val
-        sliceType reflect.Type = val.Type().Elem()
+        sliceType reflect.Type
     )
 
-    if typkind == reflect.Array {
+    switch typkind {
+    case reflect.Interface:
+        // Ziel ist any => []any
+        slice = reflect.ValueOf(make([]any, count))
+        sliceType = slice.Type().Elem()
+    case reflect.Array:
+        sliceType = val.Type().Elem()
         if uint32(val.Len()) != count {
             return er("Inappropriate array size")
         }
-    } else if typkind == reflect.Slice {
+    case reflect.Slice:
+        sliceType = val.Type().Elem()
         // Neues Slice anlegen
-
         if uint32(val.Len()) != count {
             //slice.Grow(int(count))
             slice = reflect.MakeSlice(val.Type(), int(count), int(count))
         }
-    } else if useWriting {
-        return er("Inappropriate array container -> %s", typkind.String())
+    default:
+        if useWriting {
+            return er("Inappropriate array container -> %s", typkind.String())
+        }
     }
 
     // Decode elements recursively
     for i := 0; i < int(count); i++ {
-        if sliceType.Kind() == reflect.Interface || !useWriting {
+        if !useWriting || sliceType.Kind() == reflect.Interface {
             var anyVal interface{}
             if err := unmarshalFromReader(r, &anyVal, useWriting); err != nil {
                 return err
             }
-            if useWriting {
+            if useWriting && anyVal != nil {
                 slice.Index(i).Set(reflect.ValueOf(anyVal))
             }
         } else {
@@ -93,7 +106,9 @@ func df_readArray(r *bytes.Reader, val *reflect.Value, typkind reflect.Kind, use
         }
 
     }
-    val.Set(slice)
+    if useWriting {
+        val.Set(slice)
+    }
 
     return nil
 }
@@ -108,8 +123,14 @@ func df_readObject(r *bytes.Reader, val *reflect.Value, typkind reflect.Kind, us
     //var useInterface bool = false
     var targetMapVal *reflect.Value
 
+    mapType := val.Type()
+    if typkind == reflect.Interface {
+        // Ziel ist any => map[string]any
+        mapType = reflect.TypeOf(map[string]any{})
+        typkind = reflect.Map
+    }
     if typkind == reflect.Map {
-        intermediateMap := reflect.MakeMap(val.Type())
+        intermediateMap := reflect.MakeMap(mapType)
         targetMapVal = &intermediateMap
 
     }
@@ -129,7 +150,7 @@ func df_readObject(r *bytes.Reader, val *reflect.Value, typkind reflect.Kind, us
 
         if typkind == reflect.Map {
             // If is map, put in map at appropriate location
-            elemPtr := reflect.New(val.Type().Elem())
+            elemPtr := reflect.New(mapType.Elem())
 
             if err := unmarshalFromReader(r, elemPtr.Interface(), useWriting); err != nil {
                 return err
@@ -137,7 +158,10 @@ func df_readObject(r *bytes.Reader, val *reflect.Value, typkind reflect.Kind, us
             targetMapVal.SetMapIndex(reflect.ValueOf(key), elemPtr.Elem())
         } else if typkind == reflect.Struct {
             // If is struct, put in struct
-            fieldVal := findStructFieldByTorkenTag(*val, key)
+            fieldVal, err := findStructFieldByTorkenTag(*val, key)
+            if err != nil {
+                return err
+            }
 
             if !fieldVal.IsValid() {
                 // ggf. Wert auslesen und verwerfen
@@ -306,15 +330,17 @@ func unmarshalFromReader(r *bytes.Reader, out any, useWriting bool) error {
 }
 
 // findStructFieldByTorkenTag sucht im reflect.Value nach einem Feld mit Tag `torken:"key"`.
-func findStructFieldByTorkenTag(structVal reflect.Value, key string) (reflect.Value) {
-    typ := structVal.Type()
-    for i := 0; i < typ.NumField(); i++ {
-        sf := typ.Field(i)
-        if sf.Tag.Get("torken") == key {
-            return structVal.Field(i)
+func findStructFieldByTorkenTag(structVal reflect.Value, key string) (reflect.Value, error) {
+    fields, err := torkenFields(structVal.Type())
+    if err != nil {
+        return reflect.Value{}, err
+    }
+    for _, field := range fields {
+        if field.name == key {
+            return structVal.FieldByIndex(field.index), nil
         }
     }
-    return reflect.Value{}
+    return reflect.Value{}, nil
 }
 
 // skipValue liest zur Not den aktuellen Value-Typ aus und verwirft entsprechende Daten.
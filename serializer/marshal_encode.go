@@ -76,34 +76,24 @@ func sf_PushNamed(data *flexbuffer.Flexbuf, key string) (error) {
 
 func sf_Struct(data *flexbuffer.Flexbuf, val reflect.Value, typ reflect.Type) error {
 
-    // Find fields with "torken" tag
-    var torkenFields []reflect.StructField
-    for i := 0; i < typ.NumField(); i++ {
-        fieldTyp := typ.Field(i)
-        // only use exported fields
-        if fieldTyp.PkgPath != "" {
-            continue
-        }
-        // Check for presence of "torken" tag
-        if tagVal, ok := fieldTyp.Tag.Lookup("torken"); ok && tagVal != "" {
-            torkenFields = append(torkenFields, fieldTyp)
-        }
+    // Find fields with "torken" tag, including those of embedded structs
+    torkenFields, err := torkenFields(typ)
+    if err != nil {
+        return err
     }
 
     // Make object & len entry
-    err := sf_ListBegin(data, ST_Object, uint32(len(torkenFields)))
+    err = sf_ListBegin(data, ST_Object, uint32(len(torkenFields)))
     if err != nil {
         return err
     }
 
     // Now only iterate fields with a torken tag
-    for _, fieldTyp := range torkenFields {
-        fieldVal := val.FieldByName(fieldTyp.Name)
-        // Tag-Name lesen
-        torkenName := fieldTyp.Tag.Get("torken")
+    for _, field := range torkenFields {
+        fieldVal := val.FieldByIndex(field.index)
 
         // Use tag appended field name
-        if err := sf_PushNamed(data, torkenName); err != nil {
+        if err := sf_PushNamed(data, field.name); err != nil {
             return err
         }
 
@@ -130,6 +120,10 @@ func sf_Bigint(data *flexbuffer.Flexbuf, v *big.Int) error {
 
 
 func serializeValue(data *flexbuffer.Flexbuf, v interface{}) (error) {
+    // untyped nil (e.g. JSON null in a map[string]any)
+    if v == nil {
+        return data.AppendByte(ST_Null)
+    }
     val := reflect.ValueOf(v)
     typ := reflect.TypeOf(v)
 
@@ -153,8 +147,8 @@ func serializeValue(data *flexbuffer.Flexbuf, v interface{}) (error) {
     case reflect.Uint16:     return sf_Numeric(data, ST_Uint16,  v.(uint16))
     case reflect.Int32:      return sf_Numeric(data, ST_Int32 ,  v.(int32 ))
     case reflect.Uint32:     return sf_Numeric(data, ST_Uint32,  v.(uint32))
-    case reflect.Int64:      return sf_Numeric(data, ST_Int32 ,  v.(int64 ))
-    case reflect.Uint64:     return sf_Numeric(data, ST_Uint32,  v.(uint64))
+    case reflect.Int64:      return sf_Numeric(data, ST_Int64 ,  v.(int64 ))
+    case reflect.Uint64:     return sf_Numeric(data, ST_Uint64,  v.(uint64))
     case reflect.Float32:    return sf_Numeric(data, ST_Float ,  v.(float32))
     case reflect.Float64:    return sf_Numeric(data, ST_Double,  v.(float64))
     case reflect.Bool:
@@ -0,0 +1,176 @@
+package serializer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func roundTrip(t *testing.T, in any, out any) {
+    t.Helper()
+    buf, err := Marshal(in)
+    if err != nil {
+        t.Fatalf("marshal %#v: %v", in, err)
+    }
+    if err := Unmarshal(buf, out); err != nil {
+        t.Fatalf("unmarshal %#v: %v", in, err)
+    }
+}
+
+// Untyped nil (e.g. JSON null) is encoded as null
+func TestNilValues(t *testing.T) {
+    in := map[string]any{"a": nil, "b": "x", "list": []any{nil, 1.5}}
+    var out map[string]any
+    roundTrip(t, in, &out)
+    if !reflect.DeepEqual(out, in) {
+        t.Errorf("got %#v, want %#v", out, in)
+    }
+}
+
+// Null decoded into a typed field leaves the zero value
+func TestNilIntoTyped(t *testing.T) {
+    var out map[string]string
+    roundTrip(t, map[string]any{"a": nil, "b": "x"}, &out)
+    if out["a"] != "" || out["b"] != "x" {
+        t.Errorf("got %#v", out)
+    }
+}
+
+// Objects and arrays decoded into any become map[string]any and []any
+func TestDecodeIntoAny(t *testing.T) {
+    in := map[string]any{
+        "obj":  map[string]any{"n": 2.0},
+        "list": []any{"a", map[string]any{"b": true}},
+    }
+    var out any
+    roundTrip(t, in, &out)
+    if !reflect.DeepEqual(out, in) {
+        t.Errorf("got %#v, want %#v", out, in)
+    }
+
+    var list any
+    roundTrip(t, []any{"a", 1.0}, &list)
+    if !reflect.DeepEqual(list, []any{"a", 1.0}) {
+        t.Errorf("got %#v", list)
+    }
+}
+
+// int64 and uint64 use the 64 bit types and keep their full range
+func TestInt64Values(t *testing.T) {
+    in := map[string]any{"i": int64(9007199254740993), "neg": int64(-1 << 62), "u": uint64(1<<64 - 1)}
+    var out map[string]any
+    roundTrip(t, in, &out)
+    if !reflect.DeepEqual(out, in) {
+        t.Errorf("got %#v, want %#v", out, in)
+    }
+
+    var typed struct {
+        I int64  `torken:"i"`
+        U uint64 `torken:"u"`
+    }
+    roundTrip(t, in, &typed)
+    if typed.I != 9007199254740993 || typed.U != 1<<64-1 {
+        t.Errorf("typed: got %#v", typed)
+    }
+}
+
+type embeddedBase struct {
+    Name string `torken:"name"`
+}
+
+type Base struct {
+    ID   string `torken:"id"`
+    Kind string `torken:"kind"`
+}
+
+type Inner struct {
+    Deep int32 `torken:"deep"`
+}
+
+type Middle struct {
+    Inner
+    Kind string `torken:"kind"`
+}
+
+type Outer struct {
+    Base
+    Middle
+    Kind  string `torken:"kind"` // verdeckt Base.Kind und Middle.Kind
+    Extra bool   `torken:"extra"`
+    embeddedBase
+}
+
+// Untagged embedded structs are flattened into the outer object
+func TestEmbeddedStructs(t *testing.T) {
+    in := Outer{Base: Base{ID: "a", Kind: "base"}, Kind: "outer", Extra: true}
+    in.Deep = 7
+    in.Middle.Kind = "middle"
+
+    var flat map[string]any
+    roundTrip(t, in, &flat)
+    want := map[string]any{"id": "a", "kind": "outer", "extra": true, "deep": int32(7)}
+    if !reflect.DeepEqual(flat, want) {
+        t.Errorf("flat: got %#v, want %#v", flat, want)
+    }
+
+    var out Outer
+    roundTrip(t, in, &out)
+    if out.ID != "a" || out.Kind != "outer" || !out.Extra || out.Deep != 7 || out.Base.Kind != "" {
+        t.Errorf("got %#v", out)
+    }
+}
+
+type tagged struct {
+    Base  `torken:"base"`
+    Extra bool `torken:"extra"`
+}
+
+// Embedded structs with a tag stay nested objects
+func TestTaggedEmbeddedStruct(t *testing.T) {
+    var flat map[string]any
+    roundTrip(t, tagged{Base: Base{ID: "a"}}, &flat)
+    if base, ok := flat["base"].(map[string]any); !ok || base["id"] != "a" {
+        t.Errorf("got %#v", flat)
+    }
+}
+
+type withPointer struct {
+    *Base
+}
+
+func TestEmbeddedPointerRejected(t *testing.T) {
+    if _, err := Marshal(withPointer{Base: &Base{}}); err == nil {
+        t.Error("no error for untagged embedded pointer")
+    }
+}
+
+type Audit struct {
+    By   string `torken:"by"`
+    Note string `torken:"note"`
+}
+
+type Owner struct {
+    By string `torken:"by"`
+}
+
+type ambiguous struct {
+    Audit
+    Owner
+    ID string `torken:"id"`
+}
+
+// A tag in two embedded structs at the same depth is dropped, like in encoding/json
+func TestEmbeddedAmbiguousDropped(t *testing.T) {
+    in := ambiguous{Audit: Audit{By: "audit", Note: "n"}, Owner: Owner{By: "owner"}, ID: "x"}
+    var flat map[string]any
+    roundTrip(t, in, &flat)
+    want := map[string]any{"note": "n", "id": "x"}
+    if !reflect.DeepEqual(flat, want) {
+        t.Errorf("got %#v, want %#v", flat, want)
+    }
+
+    var out ambiguous
+    roundTrip(t, map[string]any{"by": "someone", "note": "n"}, &out)
+    if out.Audit.By != "" || out.Owner.By != "" || out.Note != "n" {
+        t.Errorf("decoded into an ambiguous field: %#v", out)
+    }
+}
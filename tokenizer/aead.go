@@ -0,0 +1,111 @@
+package tokenizer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Size of the authentication tag appended by the AEAD algorithms
+const cAEAD_TAG_SIZE = 16
+
+// Whether the given algorithm carries an authentication tag in the given token version.
+// Version 1 tokens stay byte-compatible with the C library, which drops the GCM tag.
+func isAEAD(algo TAlgorithm, version uint8) bool {
+    switch algo {
+    case TALGO_AES:
+        return version >= 2
+    case TALGO_XCHACHA20:
+        return true
+    }
+    return false
+}
+
+// Whether the algorithm can be used with the given token version
+func algorithmSupported(algo TAlgorithm, version uint8) bool {
+    switch algo {
+    case TALGO_CHACHA20, TALGO_AES:
+        return true
+    case TALGO_XCHACHA20:
+        return version >= 2
+    }
+    return false
+}
+
+// Length of the random nonce salt (version >= 3 and AES version 2, not for XChaCha20)
+const cNONCE_SALT_LENGTH = 12
+
+// Mix the salt into the 96 bit part of the nonce field. The block counter /
+// validFrom in nonce[0:4] stays untouched, so the ChaCha20 counter can not overflow.
+func saltNonce(nonce []byte, salt []byte) []byte {
+    out := append([]byte(nil), nonce...)
+    for i := range salt {
+        out[4+i] ^= salt[i]
+    }
+    return out
+}
+
+func newAEAD(algo TAlgorithm, key string) (cipher.AEAD, error) {
+    switch algo {
+    case TALGO_AES:
+        block, err := aes.NewCipher(deriveKey(key))
+        if err != nil {
+            return nil, err
+        }
+        return cipher.NewGCM(block)
+    case TALGO_XCHACHA20:
+        return chacha20poly1305.NewX(deriveKey(key))
+    }
+    return nil, fmt.Errorf("%w %d", ErrUnsupportedAlgorithm, algo)
+}
+
+// IV taken from the cipher nonce (see decryptIntermediate.cipherNonce).
+// AES uses nonce[4:16], which is completely covered by the random salt. An IV
+// built from validFrom, expiresIn and the checksum alone would repeat between
+// tokens of the same second, and a repeated GCM IV leaks the authentication key.
+func aeadNonce(algo TAlgorithm, nonce []byte) []byte {
+    if algo == TALGO_XCHACHA20 {
+        return nonce
+    }
+    return nonce[4:16]
+}
+
+// Encrypt in and append the authentication tag over in and ad
+func aeadSeal(algo TAlgorithm, in []byte, key string, iv []byte, ad []byte) ([]byte, error) {
+    if len(in) == 0 || len(key) == 0 {
+        return nil, errors.New("invalid parameters")
+    }
+    aead, err := newAEAD(algo, key)
+    if err != nil {
+        return nil, err
+    }
+    if len(iv) != aead.NonceSize() {
+        return nil, errors.New("invalid parameters")
+    }
+    return aead.Seal(nil, iv, in, ad), nil
+}
+
+// Counterpart to aeadSeal, fails if the tag does not match
+func aeadOpen(algo TAlgorithm, in []byte, key string, iv []byte, ad []byte) ([]byte, error) {
+    if len(in) < cAEAD_TAG_SIZE {
+        return nil, wrapErr(ErrMalformed, "payload shorter than the authentication tag")
+    }
+    if len(key) == 0 {
+        return nil, errors.New("invalid parameters")
+    }
+    aead, err := newAEAD(algo, key)
+    if err != nil {
+        return nil, err
+    }
+    if len(iv) != aead.NonceSize() {
+        return nil, errors.New("invalid parameters")
+    }
+    out, err := aead.Open(nil, iv, in, ad)
+    if err != nil {
+        return nil, wrapErr(ErrIntegrity, "authentication tag mismatch")
+    }
+    return out, nil
+}
@@ -0,0 +1,41 @@
+package tokenizer
+
+import (
+	"errors"
+	"testing"
+)
+
+// Version 2 AES tokens of the same second, lifetime and payload must not share a GCM IV
+func TestAESv2RandomIV(t *testing.T) {
+    tk := NewTokenizer()
+    c := tk.config.Load()
+    opts := EncryptOptions().Version(2).AES().ValidFromTS(1700000000).ExpiresIn(3600)
+
+    seen := make(map[string]bool)
+    for i := 0; i < 64; i++ {
+        token, err := tk.Encrypt("same payload", "key", nil, opts)
+        if err != nil {
+            t.Fatal(err)
+        }
+        I, err := tk.int_inspect(c, token, nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if len(I.Salt) != cNONCE_SALT_LENGTH {
+            t.Fatalf("salt length %d, want %d", len(I.Salt), cNONCE_SALT_LENGTH)
+        }
+        iv := string(aeadNonce(I.Algorithm(), I.cipherNonce()))
+        if seen[iv] {
+            t.Fatalf("GCM IV repeated after %d tokens", i)
+        }
+        seen[iv] = true
+
+        var out string
+        if _, err := tk.Decrypt(token, "key", nil).Into(&out); err != nil || out != "same payload" {
+            t.Fatalf("round trip failed: %v", err)
+        }
+        if _, err := tk.Decrypt(token, "other", nil).Into(&out); !errors.Is(err, ErrIntegrity) {
+            t.Fatalf("wrong key: got %v, want ErrIntegrity", err)
+        }
+    }
+}
@@ -0,0 +1,44 @@
+package tokenizer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/thelaumix/go-torken/basex"
+)
+
+// Upper bound for cached per-call alphabets per tokenizer
+const cALPHABET_CACHE_SIZE = 64
+
+// Cache of transcoders for per-call alphabets. The cached BaseX instances are
+// never modified, so they can be used by concurrent calls.
+type alphabetCache struct {
+    mu        sync.RWMutex
+    alphabets map[string]*basex.BaseX
+}
+
+func newAlphabetCache() *alphabetCache {
+    return &alphabetCache{alphabets: make(map[string]*basex.BaseX)}
+}
+
+func (c *alphabetCache) get(alphabet string) (*basex.BaseX, error) {
+    c.mu.RLock()
+    bx, ok := c.alphabets[alphabet]
+    c.mu.RUnlock()
+    if ok {
+        return bx, nil
+    }
+
+    bx, err := basex.NewBaseX(alphabet)
+    if err != nil {
+        return nil, fmt.Errorf("invalid alphabet: %w", err)
+    }
+
+    c.mu.Lock()
+    if len(c.alphabets) >= cALPHABET_CACHE_SIZE {
+        c.alphabets = make(map[string]*basex.BaseX)
+    }
+    c.alphabets[alphabet] = bx
+    c.mu.Unlock()
+    return bx, nil
+}
@@ -0,0 +1,82 @@
+package tokenizer
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Common "who issued this, for whom" payload. It is serialized as a plain object
+// with the keys iss, sub, aud, jti, scp and ext, so the JS and C++ libraries read it
+// like any other payload. Validity times are taken from the token header.
+type StandardClaims struct {
+    Issuer   string         `torken:"iss"`
+    Subject  string         `torken:"sub"`
+    Audience []string       `torken:"aud"`
+    ID       string         `torken:"jti"`
+    Scope    Scopes         `torken:"scp"`
+    Custom   map[string]any `torken:"ext"` // application specific fields
+}
+
+// Implemented by payload types carrying standard claims, so ExpectIssuer,
+// ExpectAudience and RequireScopes can check them. *StandardClaims implements it
+// itself; a payload struct embedding StandardClaims (without a torken tag) gets
+// it promoted, and the claims are serialized flat next to its own fields.
+type StandardClaimer interface {
+    Standard() *StandardClaims
+}
+
+func (c *StandardClaims) Standard() *StandardClaims { return c }
+
+// Whether aud is one of the audiences
+func (c *StandardClaims) HasAudience(aud string) bool {
+    return slices.Contains(c.Audience, aud)
+}
+
+// Custom field by key
+func (c *StandardClaims) Get(key string) (any, bool) {
+    v, ok := c.Custom[key]
+    return v, ok
+}
+
+// Set a custom field
+func (c *StandardClaims) Set(key string, v any) *StandardClaims {
+    if c.Custom == nil {
+        c.Custom = make(map[string]any)
+    }
+    c.Custom[key] = v
+    return c
+}
+
+// Check the claims against the expectations of the decrypt options
+func (o *decryptOptions) verifyClaims(outContainer any) error {
+    if o == nil || (o.issuer == nil && o.audience == nil && len(o.scopes) == 0) {
+        return nil
+    }
+    c := standardOf(outContainer)
+    if c == nil {
+        sentinel := ErrInsufficientScope
+        if o.audience != nil {
+            sentinel = ErrAudienceMismatch
+        }
+        if o.issuer != nil {
+            sentinel = ErrIssuerMismatch
+        }
+        return fmt.Errorf("%w: payload carries no standard claims", sentinel)
+    }
+    if o.issuer != nil && c.Issuer != *o.issuer {
+        return fmt.Errorf("%w: got %q", ErrIssuerMismatch, c.Issuer)
+    }
+    if o.audience != nil && !c.HasAudience(*o.audience) {
+        return fmt.Errorf("%w: %q not in %q", ErrAudienceMismatch, *o.audience, c.Audience)
+    }
+    return o.verifyScopes(c)
+}
+
+// Standard claims of a decoded container, nil if it carries none
+func standardOf(outContainer any) *StandardClaims {
+    sc, ok := outContainer.(StandardClaimer)
+    if !ok {
+        return nil
+    }
+    return sc.Standard()
+}
@@ -0,0 +1,38 @@
+package tokenizer
+
+import (
+	"errors"
+	"testing"
+)
+
+type appClaims struct {
+    StandardClaims
+    Role string `torken:"role"`
+}
+
+// Payload types embedding StandardClaims keep the claims and satisfy StandardClaimer
+func TestEmbeddedStandardClaims(t *testing.T) {
+    tk := NewTokenizer()
+    in := appClaims{StandardClaims: StandardClaims{Issuer: "me", Audience: []string{"api"}}, Role: "admin"}
+    token, err := tk.Encrypt(in, "key", nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var out appClaims
+    if _, err := tk.Decrypt(token, "key", DecryptOptions().ExpectIssuer("me").ExpectAudience("api")).Into(&out); err != nil {
+        t.Fatalf("expectations: %v", err)
+    }
+    if out.Issuer != "me" || out.Role != "admin" {
+        t.Errorf("got %#v", out)
+    }
+    if _, err := tk.Decrypt(token, "key", DecryptOptions().ExpectIssuer("other")).Into(&out); !errors.Is(err, ErrIssuerMismatch) {
+        t.Errorf("wrong issuer: got %v, want ErrIssuerMismatch", err)
+    }
+
+    // flach serialisiert, wie ein StandardClaims-Objekt mit zusätzlichem Feld
+    var flat map[string]any
+    if _, err := tk.Decrypt(token, "key", nil).Into(&flat); err != nil || flat["iss"] != "me" || flat["role"] != "admin" {
+        t.Errorf("flat: %v %#v", err, flat)
+    }
+}
@@ -0,0 +1,29 @@
+package tokenizer
+
+import (
+	"time"
+)
+
+// Source of the current time for validFrom defaults and validity checks
+type Clock interface {
+    Now() time.Time
+}
+
+// Adapter to use a plain function as Clock
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time { return f() }
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Clock backed by time.Now, the default of every tokenizer
+func SystemClock() Clock {
+    return systemClock{}
+}
+
+// Clock that always returns t, for tests
+func FixedClock(t time.Time) Clock {
+    return ClockFunc(func() time.Time { return t })
+}
@@ -0,0 +1,73 @@
+package tokenizer
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Concurrent calls with per-call alphabets and scramblers while the tokenizer
+// defaults change. Meant to be run with -race.
+func TestConcurrentPerCallOptions(t *testing.T) {
+    tk := NewTokenizer()
+    alphabets := []string{"0123456789abcdef", "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"}
+    defaultAlphabet := tk.GetAlphabet()
+
+    var wg sync.WaitGroup
+    errs := make(chan error, 64)
+    stop := make(chan struct{})
+
+    // ändert die Standardwerte, während die anderen arbeiten
+    var setter sync.WaitGroup
+    setter.Add(1)
+    go func() {
+        defer setter.Done()
+        for i := 0; ; i++ {
+            select {
+            case <-stop:
+                return
+            default:
+            }
+            if err := tk.SetAlphabet([]string{defaultAlphabet, alphabets[1]}[i%2]); err != nil {
+                errs <- err
+                return
+            }
+            tk.SetScrambler(fmt.Sprintf("scrambler-%d", i%3))
+            tk.GetAlphabet()
+            tk.GetScrambler()
+        }
+    }()
+
+    for w := 0; w < 8; w++ {
+        wg.Add(1)
+        go func(w int) {
+            defer wg.Done()
+            alphabet := alphabets[w%2]
+            scrambler := fmt.Sprintf("worker-%d", w)
+            for i := 0; i < 50; i++ {
+                payload := fmt.Sprintf("%d/%d", w, i)
+                token, err := tk.Encrypt(payload, "key", NewIdentifier(), EncryptOptions().Alphabet(alphabet).Scrambler(scrambler))
+                if err != nil {
+                    errs <- err
+                    return
+                }
+                var out string
+                if _, err := tk.Decrypt(token, "key", DecryptOptions().Alphabet(alphabet).Scrambler(scrambler)).Into(&out); err != nil {
+                    errs <- err
+                    return
+                }
+                if out != payload {
+                    errs <- fmt.Errorf("worker %d: got %q, want %q", w, out, payload)
+                    return
+                }
+            }
+        }(w)
+    }
+    wg.Wait()
+    close(stop)
+    setter.Wait()
+    close(errs)
+    for err := range errs {
+        t.Error(err)
+    }
+}
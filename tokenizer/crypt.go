@@ -1,83 +1,31 @@
 package tokenizer
 
-/*
-#cgo CFLAGS: -I.
-#cgo LDFLAGS: -L. -ltorken_crypt_clink -lstdc++ -lssl -lcrypto -static -static-libstdc++ -static-libgcc
-#include "crypt.h"
-*/
-import "C"
 import (
+	"crypto/hmac"
 	"crypto/sha256"
 	"sort"
-    "errors"
-    "fmt"
-    "unsafe"
 )
 
 // Dein Algorithm-Enum
 type TAlgorithm byte
 
 const (
-    TALGO_CHACHA20 TAlgorithm = 0
-    TALGO_AES      TAlgorithm = 1 // 256 GCM
+    TALGO_CHACHA20  TAlgorithm = 0
+    TALGO_AES       TAlgorithm = 1 // 256 GCM
+    TALGO_XCHACHA20 TAlgorithm = 2 // XChaCha20-Poly1305, random 24 byte nonce (version >= 2)
 )
 
-// Binding to C-Version of the torken encryption algorithm
-func crpEncrypt(algo TAlgorithm, in []byte, key string, nonce []byte) ([]byte, error) {
-    // Wir nehmen an, dass out genauso groß ist wie in (ChaCha20 ohne GCM-Tag).
-    // Bei AES-GCM kann der Ciphertext größer sein (GCM-Tag).
-    // Passe ggf. die Größe an (len(in)+16 oder ähnliches).
-    out := make([]byte, len(in))
-    bytekey := []byte(key)
-
-    if len(in) == 0 && len(key) == 0 && len(nonce) == 0 {
-        return nil, errors.New("invalid parameters")
-    }
-
-    // Aufruf der C-Funktion
-    rc := C.CRP_Encrypt_EX(
-        C.int(algo),
-        (*C.uint8_t)(unsafe.Pointer(&in[0])),    // in
-        C.size_t(len(in)),
-        (*C.uint8_t)(unsafe.Pointer(&bytekey[0])),   // key
-        C.size_t(len(key)),
-        (*C.uint8_t)(unsafe.Pointer(&nonce[0])), // nonce
-        C.size_t(len(nonce)),
-        (*C.uint8_t)(unsafe.Pointer(&out[0])),   // out
-    )
-    if rc != 0 {
-        return nil, fmt.Errorf("CRP_Encrypt_EX failed with code %d", rc)
+// deriveKey mirrors DeriveKey of the C implementation: keys up to 32 bytes
+// are zero padded, longer keys are hashed down with SHA256.
+func deriveKey(key string) []byte {
+    out := make([]byte, 32)
+    if len(key) > 32 {
+        sum := sha256.Sum256([]byte(key))
+        copy(out, sum[:])
+    } else {
+        copy(out, key)
     }
-    return out, nil
-}
-
-// Binding to C-Version of the torken decryption algorithm
-func crpDecrypt(algo TAlgorithm, in []byte, key string, nonce []byte) ([]byte, error) {
-    // Wir nehmen an, dass out genauso groß ist wie in (ChaCha20 ohne GCM-Tag).
-    // Bei AES-GCM kann der Ciphertext größer sein (GCM-Tag).
-    // Passe ggf. die Größe an (len(in)+16 oder ähnliches).
-    out := make([]byte, len(in))
-    bytekey := []byte(key)
-
-    if len(in) == 0 && len(key) == 0 && len(nonce) == 0 {
-        return nil, errors.New("invalid parameters")
-    }
-
-    // Aufruf der C-Funktion
-    rc := C.CRP_Decrypt_EX(
-        C.int(algo),
-        (*C.uint8_t)(unsafe.Pointer(&in[0])),    // in
-        C.size_t(len(in)),
-        (*C.uint8_t)(unsafe.Pointer(&bytekey[0])),   // key
-        C.size_t(len(key)),
-        (*C.uint8_t)(unsafe.Pointer(&nonce[0])), // nonce
-        C.size_t(len(nonce)),
-        (*C.uint8_t)(unsafe.Pointer(&out[0])),   // out
-    )
-    if rc != 0 {
-        return nil, fmt.Errorf("CRP_Encrypt_EX failed with code %d", rc)
-    }
-    return out, nil
+    return out
 }
 
 // PseudoShuffle vertauscht data in-place basierend auf stable_sort nach key-Hash
@@ -135,3 +83,17 @@ func makeChecksum(out []byte, data []byte) {
     sum := sha256.Sum256(data)
     copy(out, sum[:8])
 }
+
+// macKey leitet den Subkey für den Token-MAC aus dem Token-Key ab
+func macKey(key string) []byte {
+    h := hmac.New(sha256.New, deriveKey(key))
+    h.Write([]byte("torken/mac"))
+    return h.Sum(nil)
+}
+
+// makeMAC: HMAC-SHA256 über data, gekürzt auf len(out) Bytes
+func makeMAC(out []byte, key []byte, data []byte) {
+    h := hmac.New(sha256.New, key)
+    h.Write(data)
+    copy(out, h.Sum(nil))
+}
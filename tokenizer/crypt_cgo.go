@@ -0,0 +1,73 @@
+//go:build cgo && torken_cgo
+
+package tokenizer
+
+/*
+#cgo CFLAGS: -I.
+#cgo LDFLAGS: -L. -ltorken_crypt_clink -lstdc++ -lssl -lcrypto -static -static-libstdc++ -static-libgcc
+#include "crypt.h"
+*/
+import "C"
+import (
+    "errors"
+    "fmt"
+    "unsafe"
+)
+
+// Binding to C-Version of the torken encryption algorithm
+func crpEncrypt(algo TAlgorithm, in []byte, key string, nonce []byte) ([]byte, error) {
+    // Wir nehmen an, dass out genauso groß ist wie in (ChaCha20 ohne GCM-Tag).
+    // Bei AES-GCM kann der Ciphertext größer sein (GCM-Tag).
+    // Passe ggf. die Größe an (len(in)+16 oder ähnliches).
+    out := make([]byte, len(in))
+    bytekey := []byte(key)
+
+    if len(in) == 0 || len(key) == 0 || len(nonce) == 0 {
+        return nil, errors.New("invalid parameters")
+    }
+
+    // Aufruf der C-Funktion
+    rc := C.CRP_Encrypt_EX(
+        C.int(algo),
+        (*C.uint8_t)(unsafe.Pointer(&in[0])),    // in
+        C.size_t(len(in)),
+        (*C.uint8_t)(unsafe.Pointer(&bytekey[0])),   // key
+        C.size_t(len(key)),
+        (*C.uint8_t)(unsafe.Pointer(&nonce[0])), // nonce
+        C.size_t(len(nonce)),
+        (*C.uint8_t)(unsafe.Pointer(&out[0])),   // out
+    )
+    if rc != 0 {
+        return nil, fmt.Errorf("CRP_Encrypt_EX failed with code %d", rc)
+    }
+    return out, nil
+}
+
+// Binding to C-Version of the torken decryption algorithm
+func crpDecrypt(algo TAlgorithm, in []byte, key string, nonce []byte) ([]byte, error) {
+    // Wir nehmen an, dass out genauso groß ist wie in (ChaCha20 ohne GCM-Tag).
+    // Bei AES-GCM kann der Ciphertext größer sein (GCM-Tag).
+    // Passe ggf. die Größe an (len(in)+16 oder ähnliches).
+    out := make([]byte, len(in))
+    bytekey := []byte(key)
+
+    if len(in) == 0 || len(key) == 0 || len(nonce) == 0 {
+        return nil, errors.New("invalid parameters")
+    }
+
+    // Aufruf der C-Funktion
+    rc := C.CRP_Decrypt_EX(
+        C.int(algo),
+        (*C.uint8_t)(unsafe.Pointer(&in[0])),    // in
+        C.size_t(len(in)),
+        (*C.uint8_t)(unsafe.Pointer(&bytekey[0])),   // key
+        C.size_t(len(key)),
+        (*C.uint8_t)(unsafe.Pointer(&nonce[0])), // nonce
+        C.size_t(len(nonce)),
+        (*C.uint8_t)(unsafe.Pointer(&out[0])),   // out
+    )
+    if rc != 0 {
+        return nil, fmt.Errorf("CRP_Decrypt_EX failed with code %d", rc)
+    }
+    return out, nil
+}
@@ -0,0 +1,95 @@
+//go:build !cgo || !torken_cgo
+
+package tokenizer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+// xorStream applies the keystream of the given algorithm onto in.
+//
+// The 16 byte nonce is interpreted the same way OpenSSL does it for the C version:
+//   - ChaCha20: nonce[0:4] is the little-endian block counter, nonce[4:16] the 96 bit nonce
+//   - AES-256-GCM: nonce[0:12] is the IV, the GCM tag is not part of the output
+func xorStream(algo TAlgorithm, in []byte, key string, nonce []byte) ([]byte, error) {
+    if len(in) == 0 || len(key) == 0 || len(nonce) != 16 {
+        return nil, errors.New("invalid parameters")
+    }
+
+    out := make([]byte, len(in))
+    dk := deriveKey(key)
+
+    switch algo {
+    case TALGO_CHACHA20:
+        if err := chachaXOR(out, in, dk, nonce); err != nil {
+            return nil, err
+        }
+
+    case TALGO_AES:
+        block, err := aes.NewCipher(dk)
+        if err != nil {
+            return nil, err
+        }
+        // GCM beginnt die Verschlüsselung mit Counter-Block J0+1 = IV || 0x00000002
+        iv := make([]byte, aes.BlockSize)
+        copy(iv, nonce[0:12])
+        binary.BigEndian.PutUint32(iv[12:], 2)
+        cipher.NewCTR(block, iv).XORKeyStream(out, in)
+
+    default:
+        return nil, fmt.Errorf("%w %d", ErrUnsupportedAlgorithm, algo)
+    }
+    return out, nil
+}
+
+// Pure Go version of the torken encryption algorithm
+func crpEncrypt(algo TAlgorithm, in []byte, key string, nonce []byte) ([]byte, error) {
+    return xorStream(algo, in, key, nonce)
+}
+
+// Pure Go version of the torken decryption algorithm
+func crpDecrypt(algo TAlgorithm, in []byte, key string, nonce []byte) ([]byte, error) {
+    return xorStream(algo, in, key, nonce)
+}
+
+const cCHACHA_BLOCK_SIZE = 64
+
+// ChaCha20 with a 32 bit block counter that carries into nonce[4:8] on overflow,
+// like OpenSSL does. The counter comes from the unauthenticated validFrom, so it
+// can be anywhere up to 0xFFFFFFFF (x/crypto/chacha20 would panic on the wrap).
+func chachaXOR(out, in []byte, dk []byte, nonce []byte) error {
+    counter := binary.LittleEndian.Uint32(nonce[0:4])
+    iv := append([]byte(nil), nonce[4:16]...)
+
+    // Bytes bis zum Überlauf des Counters
+    remaining := (uint64(1)<<32 - uint64(counter)) * cCHACHA_BLOCK_SIZE
+    n := len(in)
+    if uint64(n) > remaining {
+        n = int(remaining)
+    }
+
+    c, err := chacha20.NewUnauthenticatedCipher(dk, iv)
+    if err != nil {
+        return err
+    }
+    c.SetCounter(counter)
+    c.XORKeyStream(out[:n], in[:n])
+    if n == len(in) {
+        return nil
+    }
+
+    // Übertrag: Counter beginnt bei 0, nächstes Nonce-Wort wird erhöht
+    binary.LittleEndian.PutUint32(iv[0:4], binary.LittleEndian.Uint32(iv[0:4])+1)
+    c, err = chacha20.NewUnauthenticatedCipher(dk, iv)
+    if err != nil {
+        return err
+    }
+    c.XORKeyStream(out[n:], in[n:])
+    return nil
+}
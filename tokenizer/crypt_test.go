@@ -0,0 +1,98 @@
+package tokenizer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Keystream across the 32 bit ChaCha20 block counter wrap. The digests were taken
+// from the OpenSSL backend (-tags torken_cgo), both backends have to match them.
+func TestChaCha20CounterCarry(t *testing.T) {
+    in := make([]byte, 300)
+    for i := range in {
+        in[i] = byte(i)
+    }
+    cases := []struct {
+        counter uint32
+        digest  string
+    }{
+        {0xFFFFFFFF, "4c294d2c62d5baa784a09e7d729c26362169ad17c0f1012a81aad0cd3003e41c"},
+        {0xFFFFFFFE, "34066bae0cd004ebdb57777a493e5475d4ae4bd58c87fc02aa456e9bd3d8d498"},
+        {5,          "93e0f76ac048a142852e21151e0fd0b553a6958f9cf788c1e51de532a27a87db"},
+    }
+    for _, tc := range cases {
+        nonce := []byte{0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 9, 10, 11, 12, 13, 14, 15, 16}
+        binary.LittleEndian.PutUint32(nonce, tc.counter)
+
+        out, err := crpEncrypt(TALGO_CHACHA20, in, "key", nonce)
+        if err != nil {
+            t.Fatalf("counter %08x: %v", tc.counter, err)
+        }
+        sum := sha256.Sum256(out)
+        if got := hex.EncodeToString(sum[:]); got != tc.digest {
+            t.Errorf("counter %08x: keystream digest %s, want %s", tc.counter, got, tc.digest)
+        }
+        back, err := crpDecrypt(TALGO_CHACHA20, out, "key", nonce)
+        if err != nil || !bytes.Equal(back, in) {
+            t.Errorf("counter %08x: round trip failed: %v", tc.counter, err)
+        }
+    }
+}
+
+// A v1 token with validFrom = 0xFFFFFFFF puts the cipher counter right before the
+// wrap. Decrypting it with the wrong key must fail the checksum, not panic.
+func TestCounterOverflowToken(t *testing.T) {
+    tk := NewTokenizer()
+    payload := map[string]string{"data": string(bytes.Repeat([]byte("x"), 200))}
+    token, err := tk.Encrypt(payload, "key", nil, EncryptOptions().Version(1).ValidFromTS(0xFFFFFFFF))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var out map[string]string
+    if _, err := tk.Decrypt(token, "other", nil).Into(&out); !errors.Is(err, ErrIntegrity) {
+        t.Errorf("wrong key: got %v, want ErrIntegrity", err)
+    }
+    if _, err := tk.Decrypt(token, "key", nil).Into(&out); err != nil || out["data"] != payload["data"] {
+        t.Errorf("round trip failed: %v", err)
+    }
+}
+
+// v3 ChaCha20 tokens issued in the same second with the same payload and
+// identifier still get their own nonce salt and never share a keystream
+func TestNonceSaltPerToken(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    tk := clockTokenizer(t, &now)
+    id := NewIdentifier()
+
+    salts := make(map[string]bool)
+    tokens := make(map[string]bool)
+    for i := 0; i < 64; i++ {
+        token, err := tk.Encrypt("same payload", "key", id, EncryptOptions().Version(3).ChaCha20())
+        if err != nil {
+            t.Fatal(err)
+        }
+        I, err := tk.int_inspect(tk.config.Load(), token, nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if I.Version() != 3 || len(I.Salt) != cNONCE_SALT_LENGTH {
+            t.Fatalf("version %d, salt length %d", I.Version(), len(I.Salt))
+        }
+        salts[string(I.Salt)] = true
+        tokens[token] = true
+
+        var out string
+        if _, err := tk.Decrypt(token, "key", nil).Into(&out); err != nil || out != "same payload" {
+            t.Fatalf("decrypt: %q, %v", out, err)
+        }
+    }
+    if len(salts) != 64 || len(tokens) != 64 {
+        t.Errorf("%d distinct salts, %d distinct tokens out of 64", len(salts), len(tokens))
+    }
+}
@@ -0,0 +1,178 @@
+package tokenizer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cutoffs for "every token issued before T is invalid", per identifier or for all
+// tokens. The token's validFrom is taken as its issue time, with second precision:
+// tokens issued within the second of a bump stay valid.
+type EpochStore interface {
+    // Cutoff for tokens with the given identifier, the later of the identifier's
+    // and the global epoch. Zero if there is none.
+    Epoch(id Identifier) (time.Time, error)
+    // Invalidate every token of the identifier issued before at
+    Bump(id Identifier, at time.Time) error
+    // Invalidate every token issued before at, including anonymous ones
+    BumpGlobal(at time.Time) error
+}
+
+// Epoch check of a verified token
+func (d *decryptIntermediate) checkEpoch() error {
+    if d.config == nil || d.config.epochs == nil {
+        return nil
+    }
+    id, err := NewIdentifierFromBytes(d.Identifier)
+    if err != nil {
+        return err
+    }
+    cutoff, err := d.config.epochs.Epoch(*id)
+    if err != nil {
+        return err
+    }
+    if !cutoff.IsZero() && int64(d.ValidFrom) < cutoff.Unix() {
+        return fmt.Errorf("%w: issued %d, epoch %d", ErrSuperseded, d.ValidFrom, cutoff.Unix())
+    }
+    return nil
+}
+
+// In-memory epoch store. Epochs only move forward.
+type MemoryEpochStore struct {
+    mu     sync.RWMutex
+    epochs map[string]time.Time // identifier => cutoff
+    global time.Time
+}
+
+func NewMemoryEpochStore() *MemoryEpochStore {
+    return &MemoryEpochStore{epochs: make(map[string]time.Time)}
+}
+
+func (s *MemoryEpochStore) Epoch(id Identifier) (time.Time, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    cutoff := s.global
+    if !id.Anonymous() {
+        if e := s.epochs[string(id.Bytes())]; e.After(cutoff) {
+            cutoff = e
+        }
+    }
+    return cutoff, nil
+}
+
+func (s *MemoryEpochStore) Bump(id Identifier, at time.Time) error {
+    if id.Anonymous() {
+        return s.BumpGlobal(at)
+    }
+    s.set(string(id.Bytes()), at)
+    return nil
+}
+
+func (s *MemoryEpochStore) BumpGlobal(at time.Time) error {
+    s.set("", at)
+    return nil
+}
+
+// Epoch setzen, leerer Schlüssel = global
+func (s *MemoryEpochStore) set(id string, at time.Time) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if id == "" {
+        if at.After(s.global) {
+            s.global = at
+        }
+        return
+    }
+    if at.After(s.epochs[id]) {
+        s.epochs[id] = at
+    }
+}
+
+// File-backed epoch store that survives restarts. Bumps are appended as
+// "<identifier hex | *> <unix>" lines, the file is compacted when opened.
+type FileEpochStore struct {
+    mem *MemoryEpochStore
+    log *fileLog
+}
+
+// Open or create the epoch file at path
+func NewFileEpochStore(path string) (*FileEpochStore, error) {
+    mem := NewMemoryEpochStore()
+    log, err := openFileLog(path, func(line string) {
+        if id, at, ok := parseEpoch(line); ok {
+            mem.set(id, at)
+        }
+    }, func() []string {
+        lines := make([]string, 0, len(mem.epochs)+1)
+        if !mem.global.IsZero() {
+            lines = append(lines, formatEpoch("", mem.global))
+        }
+        for id, at := range mem.epochs {
+            lines = append(lines, formatEpoch(id, at))
+        }
+        return lines
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &FileEpochStore{mem: mem, log: log}, nil
+}
+
+func (s *FileEpochStore) Epoch(id Identifier) (time.Time, error) {
+    return s.mem.Epoch(id)
+}
+
+func (s *FileEpochStore) Bump(id Identifier, at time.Time) error {
+    if id.Anonymous() {
+        return s.BumpGlobal(at)
+    }
+    if err := s.log.append(formatEpoch(string(id.Bytes()), at)); err != nil {
+        return err
+    }
+    s.mem.set(string(id.Bytes()), at)
+    return nil
+}
+
+func (s *FileEpochStore) BumpGlobal(at time.Time) error {
+    if err := s.log.append(formatEpoch("", at)); err != nil {
+        return err
+    }
+    s.mem.set("", at)
+    return nil
+}
+
+// Close the underlying file
+func (s *FileEpochStore) Close() error {
+    return s.log.close()
+}
+
+func formatEpoch(id string, at time.Time) string {
+    key := "*"
+    if id != "" {
+        key = hex.EncodeToString([]byte(id))
+    }
+    return fmt.Sprintf("%s %d", key, at.Unix())
+}
+
+func parseEpoch(line string) (string, time.Time, bool) {
+    key, ts, found := strings.Cut(line, " ")
+    if !found {
+        return "", time.Time{}, false
+    }
+    unix, err := strconv.ParseInt(ts, 10, 64)
+    if err != nil {
+        return "", time.Time{}, false
+    }
+    if key == "*" {
+        return "", time.Unix(unix, 0), true
+    }
+    id, err := hex.DecodeString(key)
+    if err != nil || len(id) != cIDENTIFIER_LENGTH {
+        return "", time.Time{}, false
+    }
+    return string(id), time.Unix(unix, 0), true
+}
@@ -0,0 +1,148 @@
+package tokenizer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func epochTokenizer(t *testing.T, now *time.Time, store EpochStore) *Tokenizer {
+    t.Helper()
+    tk, err := NewTokenizerWith(TokenizerOptions().
+        Clock(ClockFunc(func() time.Time { return *now })).
+        EpochStore(store))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return tk
+}
+
+func TestEpochBump(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    store := NewMemoryEpochStore()
+    tk := epochTokenizer(t, &now, store)
+
+    alice, bob := NewIdentifier(), NewIdentifier()
+    aliceToken, _ := tk.Encrypt("a", "key", alice, nil)
+    bobToken, _ := tk.Encrypt("b", "key", bob, nil)
+    anonToken, _ := tk.Encrypt("anon", "key", nil, nil)
+
+    now = now.Add(time.Minute)
+    if err := store.Bump(*alice, now); err != nil {
+        t.Fatal(err)
+    }
+    var out string
+    if _, err := tk.Decrypt(aliceToken, "key", nil).Into(&out); !errors.Is(err, ErrSuperseded) {
+        t.Errorf("alice: got %v, want ErrSuperseded", err)
+    }
+    if _, err := tk.Decrypt(bobToken, "key", nil).Into(&out); err != nil {
+        t.Errorf("bob: %v", err)
+    }
+    // anonyme Tokens hängen nur an der globalen Epoche
+    if _, err := tk.Decrypt(anonToken, "key", nil).Into(&out); err != nil {
+        t.Errorf("anonymous: %v", err)
+    }
+
+    // Epochen laufen nur vorwärts
+    store.Bump(*alice, now.Add(-time.Hour))
+    if e, _ := store.Epoch(*alice); !e.Equal(now) {
+        t.Errorf("epoch moved back to %v", e)
+    }
+
+    if err := store.BumpGlobal(now); err != nil {
+        t.Fatal(err)
+    }
+    for name, token := range map[string]string{"bob": bobToken, "anonymous": anonToken} {
+        if _, err := tk.Decrypt(token, "key", nil).Into(&out); !errors.Is(err, ErrSuperseded) {
+            t.Errorf("%s after global bump: got %v, want ErrSuperseded", name, err)
+        }
+    }
+    if e, _ := store.Epoch(*NewIdentifierAnonymous()); !e.Equal(now) {
+        t.Errorf("anonymous epoch %v, want %v", e, now)
+    }
+
+    // Bump einer anonymen Identität ist global
+    later := now.Add(time.Minute)
+    store.Bump(*NewIdentifierAnonymous(), later)
+    if e, _ := store.Epoch(*bob); !e.Equal(later) {
+        t.Errorf("bob after anonymous bump: %v, want %v", e, later)
+    }
+}
+
+// Tokens issued within the second of a bump stay valid (second precision)
+func TestEpochSameSecond(t *testing.T) {
+    now := time.Unix(1700000000, 0).Add(200 * time.Millisecond)
+    store := NewMemoryEpochStore()
+    tk := epochTokenizer(t, &now, store)
+    id := NewIdentifier()
+
+    before, _ := tk.Encrypt("before", "key", id, nil)
+    now = now.Add(500 * time.Millisecond)
+    store.Bump(*id, now)
+    same, _ := tk.Encrypt("same", "key", id, nil)
+    now = now.Add(time.Second)
+    after, _ := tk.Encrypt("after", "key", id, nil)
+
+    var out string
+    for name, token := range map[string]string{"before": before, "same second": same, "after": after} {
+        if _, err := tk.Decrypt(token, "key", nil).Into(&out); err != nil {
+            t.Errorf("%s: %v", name, err)
+        }
+    }
+
+    // eine Sekunde später zählt
+    store.Bump(*id, now)
+    if _, err := tk.Decrypt(same, "key", nil).Into(&out); !errors.Is(err, ErrSuperseded) {
+        t.Errorf("previous second: got %v, want ErrSuperseded", err)
+    }
+    if _, err := tk.Decrypt(after, "key", nil).Into(&out); err != nil {
+        t.Errorf("bump second: %v", err)
+    }
+}
+
+func TestFileEpochStoreReopen(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "epochs")
+    at := time.Unix(1700000000, 0)
+    id := NewIdentifier()
+
+    s, err := NewFileEpochStore(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for i := 0; i < 3; i++ {
+        if err := s.Bump(*id, at.Add(time.Duration(i)*time.Minute)); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := s.BumpGlobal(at); err != nil {
+        t.Fatal(err)
+    }
+    s.Close()
+    if err := s.BumpGlobal(at); err == nil {
+        t.Error("bump after close")
+    }
+
+    s, err = NewFileEpochStore(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer s.Close()
+    if e, _ := s.Epoch(*id); !e.Equal(at.Add(2 * time.Minute)) {
+        t.Errorf("identifier epoch after reopen: %v", e)
+    }
+    if e, _ := s.Epoch(*NewIdentifier()); !e.Equal(at) {
+        t.Errorf("global epoch after reopen: %v", e)
+    }
+
+    // kompaktiert auf eine Zeile pro Identität plus die globale
+    data, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+        t.Errorf("compacted file: %q", data)
+    }
+}
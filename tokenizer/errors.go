@@ -0,0 +1,78 @@
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/thelaumix/go-torken/basex"
+)
+
+// Sentinel errors. Use errors.Is to check for them, they are usually wrapped
+// in a *TokenError carrying the stage the failure happened in.
+var (
+    ErrMalformed            = errors.New("malformed token")
+    ErrIntegrity            = errors.New("token integrity invalid")
+    ErrExpired              = errors.New("token expired")
+    ErrNotYetValid          = errors.New("token not yet valid")
+    ErrUnsupportedVersion   = errors.New("unsupported token version")
+    ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+    ErrInvalidAlphabetChar  = basex.ErrInvalidChar
+    ErrKeyDerivation        = errors.New("invalid key derivation")
+    ErrUnknownKey           = errors.New("unknown key id")
+    ErrKeyRetired           = errors.New("key has been retired")
+    ErrRevoked              = errors.New("token has been revoked")
+    ErrSuperseded           = errors.New("token issued before the current epoch")
+    ErrLifetimeExceeded     = errors.New("maximum token lifetime exceeded")
+    ErrReplayed             = errors.New("single-use token has already been used")
+    ErrIssuerMismatch       = errors.New("unexpected token issuer")
+    ErrAudienceMismatch     = errors.New("token not intended for this audience")
+    ErrInsufficientScope    = errors.New("token lacks a required scope")
+    ErrFingerprintMismatch  = errors.New("token bound to another client")
+)
+
+// Stage of the token processing an error happened in
+type Stage string
+
+const (
+    STAGE_ENCRYPT   Stage = "encrypt"   // building a token
+    STAGE_DECODE    Stage = "decode"    // baseX decoding
+    STAGE_PARSE     Stage = "parse"     // reading the token header
+    STAGE_KEY       Stage = "key"       // key resolution and derivation
+    STAGE_DECRYPT   Stage = "decrypt"   // cipher
+    STAGE_VERIFY    Stage = "verify"    // checksum / MAC check
+    STAGE_VALIDATE  Stage = "validate"  // validity period
+    STAGE_CHECK     Stage = "check"     // revocation, epoch and replay checks after decryption
+    STAGE_UNMARSHAL Stage = "unmarshal" // decoding the payload into the container
+    STAGE_CLAIMS    Stage = "claims"    // issuer, audience and scope expectations
+)
+
+// Error returned by the tokenizer, carrying the stage and the underlying cause
+type TokenError struct {
+    Stage Stage
+    Err   error
+}
+
+func (e *TokenError) Error() string {
+    return fmt.Sprintf("torken %s: %v", e.Stage, e.Err)
+}
+
+func (e *TokenError) Unwrap() error {
+    return e.Err
+}
+
+// Wrap err into a *TokenError for the given stage. Existing TokenErrors are passed through.
+func tkErr(stage Stage, err error) error {
+    if err == nil {
+        return nil
+    }
+    var te *TokenError
+    if errors.As(err, &te) {
+        return err
+    }
+    return &TokenError{Stage: stage, Err: err}
+}
+
+// Wrap a sentinel with some detail
+func wrapErr(sentinel error, detail string) error {
+    return fmt.Errorf("%w: %s", sentinel, detail)
+}
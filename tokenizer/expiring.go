@@ -0,0 +1,38 @@
+package tokenizer
+
+import (
+	"time"
+)
+
+// Interval between sweeps of expired entries in the memory stores
+const cSWEEP_INTERVAL = time.Minute
+
+// Keys with a drop time, shared by the memory stores. A zero drop time keeps
+// the key forever. Not synchronized, the owning store holds its lock.
+type expiringSet struct {
+    m         map[string]time.Time
+    nextSweep time.Time
+}
+
+func newExpiringSet() expiringSet {
+    return expiringSet{m: make(map[string]time.Time)}
+}
+
+// Whether key is present and not due to be dropped at now
+func (s *expiringSet) live(key string, now time.Time) bool {
+    until, ok := s.m[key]
+    return ok && (until.IsZero() || now.Before(until))
+}
+
+// Abgelaufene Einträge entfernen, höchstens einmal pro Intervall
+func (s *expiringSet) sweep(now time.Time) {
+    if now.Before(s.nextSweep) {
+        return
+    }
+    s.nextSweep = now.Add(cSWEEP_INTERVAL)
+    for key, until := range s.m {
+        if !until.IsZero() && !now.Before(until) {
+            delete(s.m, key)
+        }
+    }
+}
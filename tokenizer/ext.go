@@ -15,6 +15,9 @@ type Identifier struct {
 }
 
 func (id *Identifier) Bytes() []byte {
+    if id == nil {
+        return nil
+    }
     return id.id
 }
 func (id *Identifier) Hex() string {
@@ -88,6 +91,29 @@ func (t *Tokenizer) EncryptAno(payload any, key string, options *encryptOptions)
 }
 
 
+// Encrypt with the active key of a key ring. The key id is recorded in the token header.
+// A nil identifier encrypts anonymously.
+func (t *Tokenizer) EncryptRing(payload any, ring *KeyRing, identifier *Identifier, options *encryptOptions) (string, error) {
+    key, keyID, err := ring.activeKey()
+    if err != nil {return "", err}
+
+    opts := EncryptOptions()
+    if options != nil {
+        copied := *options
+        opts = &copied
+    }
+    opts.keyID = &keyID
+
+    marshaled, err := serializer.Marshal(payload)
+    if err != nil {return "", err}
+    var id []byte
+    if identifier != nil {
+        id = identifier.Bytes()
+    }
+    return t.int_encrypt(id, key, marshaled, opts)
+}
+
+
 type decryptHandle struct {
     err error
     data []byte
@@ -100,12 +126,19 @@ func (h *decryptHandle) Into(outContainer any) (*tkResult, error) {
         return nil, h.err
     }
     err := serializer.Unmarshal(h.data, outContainer)
-    if err != nil { return nil, err }
+    if err != nil { return nil, tkErr(STAGE_UNMARSHAL, err) }
+    if err := h.i.options.verifyClaims(outContainer); err != nil {
+        return nil, tkErr(STAGE_CLAIMS, err)
+    }
+    // zuletzt, damit abgelehnte Tokens nicht verbraucht werden
+    if err := h.i.checkReplay(); err != nil {
+        return nil, tkErr(STAGE_CHECK, err)
+    }
 
     var ID *Identifier
     if h.i.UsesIdentifier() {
         ID, err = NewIdentifierFromBytes(h.i.Identifier)
-        if err != nil { return nil, err }
+        if err != nil { return nil, tkErr(STAGE_PARSE, err) }
     } else {
         ID = NewIdentifierAnonymous()
     }
@@ -114,9 +147,12 @@ func (h *decryptHandle) Into(outContainer any) (*tkResult, error) {
         validFrom: time.Unix(int64(h.i.ValidFrom), 0),
         expiresIn: h.i.ExpiresIn,
         isValid: h.i.IsValid,
+        validityErr: h.i.ValidityErr,
         identifier: *ID,
         version: uint8(h.i.Version()),
         algorithm: h.i.Algorithm(),
+        keyID: h.i.KeyID,
+        scopes: scopesOf(outContainer),
     }, nil
 }
 
@@ -141,9 +177,12 @@ type tkResult struct {
     validFrom   time.Time
     expiresIn   uint32
     isValid     bool
+    validityErr error
     identifier  Identifier
     version     uint8
     algorithm   TAlgorithm
+    keyID       *KeyID
+    scopes      Scopes
 }
 
 
@@ -154,12 +193,36 @@ func (r *tkResult) ValidFrom() time.Time { return r.validFrom }
 func (r *tkResult) ExpiresIn() uint32    { return r.expiresIn }
 // Whether the token is valid
 func (r *tkResult) IsValid() bool        { return r.isValid }
+// Why the token is not valid (ErrExpired or ErrNotYetValid), nil if it is
+func (r *tkResult) Err() error           { return r.validityErr }
 // The identifier for this token
 func (r *tkResult) Identifier() Identifier { return r.identifier }
 // Torken version this token has been created with
 func (r *tkResult) Version() uint8       { return r.version }
 // Used encryption algorithm
 func (r *tkResult) Algorithm() TAlgorithm { return r.algorithm }
+// Key ring key id recorded in the token, if any
+func (r *tkResult) KeyID() (KeyID, bool) {
+    if r.keyID == nil {
+        return KeyID{}, false
+    }
+    return *r.keyID, true
+}
+// Scopes granted by the StandardClaims of the payload, empty if it carries none
+func (r *tkResult) Scopes() Scopes       { return r.scopes }
+// Whether the payload grants scope, see Scopes
+func (r *tkResult) HasScope(scope string) bool { return r.scopes.HasScope(scope) }
+// Whether the payload grants all of the scopes
+func (r *tkResult) HasAll(scopes ...string) bool { return r.scopes.HasAll(scopes...) }
+// Whether the payload grants at least one of the scopes
+func (r *tkResult) HasAny(scopes ...string) bool { return r.scopes.HasAny(scopes...) }
+
+func scopesOf(outContainer any) Scopes {
+    if c := standardOf(outContainer); c != nil {
+        return c.Scope
+    }
+    return nil
+}
 
 
 // Decrypt a buffer
@@ -180,7 +243,7 @@ func (t *Tokenizer) DecryptFn(token string, keyResolver func(identifier Identifi
     var key string
     if I.UsesIdentifier() {
         ID, err = NewIdentifierFromBytes(I.Identifier)
-        if err != nil {return decHandleErr(err)}
+        if err != nil {return decHandleErr(tkErr(STAGE_PARSE, err))}
     } else {
         ID = NewIdentifierAnonymous()
     }
@@ -191,10 +254,16 @@ func (t *Tokenizer) DecryptFn(token string, keyResolver func(identifier Identifi
     return decHandleBuf(I, byt)
 }
 
+// Decrypt a token with a key ring. The key is picked by the key id in the token header,
+// tokens without a key id are decrypted with the active key. Retirement is checked against
+// the tokenizer clock, ValidateAt does not bring a retired key back.
+func (t *Tokenizer) DecryptRing(token string, ring *KeyRing, options *decryptOptions) *decryptHandle {
+    I, err := t.int_decrypt_begin(token, options)
+    if err != nil {return decHandleErr(err)}
+    key, err := ring.resolve(I.KeyID, I.config.now())
+    if err != nil {return decHandleErr(tkErr(STAGE_KEY, err))}
+    byt, err := t.int_decrypt_finalize(I, key)
+    if err != nil {return decHandleErr(err)}
 
-
-
-
-
-
-
+    return decHandleBuf(I, byt)
+}
@@ -0,0 +1,95 @@
+package tokenizer
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Append-only line log backing the file stores. On open every line is handed to
+// load, then the file is rewritten with the lines returned by snapshot.
+type fileLog struct {
+    mu   sync.Mutex
+    path string
+    f    *os.File
+}
+
+// Open the log at path, calling load for every existing line and
+// compacting it to the lines returned by snapshot.
+func openFileLog(path string, load func(line string), snapshot func() []string) (*fileLog, error) {
+    in, err := os.Open(path)
+    if err == nil {
+        sc := bufio.NewScanner(in)
+        for sc.Scan() {
+            line := strings.TrimSpace(sc.Text())
+            if len(line) == 0 || line[0] == '#' {
+                continue
+            }
+            load(line)
+        }
+        err = sc.Err()
+        in.Close()
+        if err != nil {
+            return nil, err
+        }
+    } else if !errors.Is(err, os.ErrNotExist) {
+        return nil, err
+    }
+
+    // kompaktieren: temporäre Datei schreiben und umbenennen
+    tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+    if err != nil {
+        return nil, err
+    }
+    w := bufio.NewWriter(tmp)
+    for _, line := range snapshot() {
+        w.WriteString(line)
+        w.WriteByte('\n')
+    }
+    if err = w.Flush(); err == nil {
+        err = tmp.Sync()
+    }
+    if cerr := tmp.Close(); err == nil {
+        err = cerr
+    }
+    if err == nil {
+        err = os.Rename(tmp.Name(), path)
+    }
+    if err != nil {
+        os.Remove(tmp.Name())
+        return nil, err
+    }
+
+    f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+    if err != nil {
+        return nil, err
+    }
+    return &fileLog{path: path, f: f}, nil
+}
+
+// Append a line and sync it to disk
+func (l *fileLog) append(line string) error {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if l.f == nil {
+        return os.ErrClosed
+    }
+    if _, err := l.f.WriteString(line + "\n"); err != nil {
+        return err
+    }
+    return l.f.Sync()
+}
+
+func (l *fileLog) close() error {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if l.f == nil {
+        return nil
+    }
+    err := l.f.Close()
+    l.f = nil
+    return err
+}
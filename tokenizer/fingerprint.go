@@ -0,0 +1,37 @@
+package tokenizer
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// Digest of a client fingerprint as it is bound into the MAC. Only the binding
+// is recorded in the header (hfFINGERPRINT), the fingerprint itself is not stored.
+func fingerprintDigest(fingerprint []byte) []byte {
+    h := sha256.New()
+    h.Write([]byte("torken/fingerprint\x00"))
+    h.Write(fingerprint)
+    return h.Sum(nil)
+}
+
+// Fingerprint check of a bound token, before decryption. A token opened with a
+// different fingerprint fails the integrity check instead.
+func (d *decryptIntermediate) checkFingerprint() error {
+    if d.Flags&hfFINGERPRINT == 0 {
+        return nil
+    }
+    if d.Fingerprint == nil {
+        return wrapErr(ErrFingerprintMismatch, "token is bound to a client fingerprint, none given")
+    }
+    return nil
+}
+
+// Integrity failures of bound tokens also report ErrFingerprintMismatch. The
+// fingerprint is not stored, so another client and tampering look the same.
+func (d *decryptIntermediate) fingerprintErr(err error) error {
+    if d.Flags&hfFINGERPRINT == 0 || !errors.Is(err, ErrIntegrity) {
+        return err
+    }
+    return fmt.Errorf("%w: %w", ErrFingerprintMismatch, err)
+}
@@ -0,0 +1,67 @@
+package tokenizer
+
+import (
+	"errors"
+	"testing"
+)
+
+// A missing fingerprint must not silently issue an unbound token
+func TestFingerprintRequiredOnEncrypt(t *testing.T) {
+    tk := NewTokenizer()
+    for _, fp := range [][]byte{nil, {}} {
+        if _, err := tk.Encrypt("x", "key", nil, EncryptOptions().Fingerprint(fp)); !errors.Is(err, ErrFingerprintMismatch) {
+            t.Errorf("fingerprint %#v: got %v, want ErrFingerprintMismatch", fp, err)
+        }
+    }
+}
+
+func TestFingerprintMismatch(t *testing.T) {
+    tk := NewTokenizer()
+    for _, algo := range []TAlgorithm{TALGO_CHACHA20, TALGO_AES, TALGO_XCHACHA20} {
+        token, err := tk.Encrypt("x", "key", nil, EncryptOptions().Algorithm(algo).Fingerprint([]byte("client-a")))
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        var out string
+        if _, err := tk.Decrypt(token, "key", DecryptOptions().Fingerprint([]byte("client-a"))).Into(&out); err != nil {
+            t.Errorf("algo %d, same client: %v", algo, err)
+        }
+        _, err = tk.Decrypt(token, "key", DecryptOptions().Fingerprint([]byte("client-b"))).Into(&out)
+        if !errors.Is(err, ErrFingerprintMismatch) || !errors.Is(err, ErrIntegrity) {
+            t.Errorf("algo %d, other client: got %v, want ErrFingerprintMismatch and ErrIntegrity", algo, err)
+        }
+        _, err = tk.Decrypt(token, "key", nil).Into(&out)
+        if !errors.Is(err, ErrFingerprintMismatch) {
+            t.Errorf("algo %d, no fingerprint: got %v, want ErrFingerprintMismatch", algo, err)
+        }
+    }
+
+    // ungebundene Tokens: Integritätsfehler ohne ErrFingerprintMismatch
+    token, _ := tk.Encrypt("x", "key", nil, nil)
+    var out string
+    _, err := tk.Decrypt(token, "other", DecryptOptions().Fingerprint([]byte("client-a"))).Into(&out)
+    if !errors.Is(err, ErrIntegrity) || errors.Is(err, ErrFingerprintMismatch) {
+        t.Errorf("unbound, wrong key: got %v", err)
+    }
+}
+
+// Refreshing keeps the binding to the same client
+func TestFingerprintRefresh(t *testing.T) {
+    tk := NewTokenizer()
+    token, err := tk.Encrypt("x", "key", nil, EncryptOptions().Fingerprint([]byte("client-a")))
+    if err != nil {
+        t.Fatal(err)
+    }
+    refreshed, err := tk.Refresh(token, "key", RefreshOptions().Fingerprint([]byte("client-a")))
+    if err != nil {
+        t.Fatal(err)
+    }
+    var out string
+    if _, err := tk.Decrypt(refreshed, "key", DecryptOptions().Fingerprint([]byte("client-b"))).Into(&out); !errors.Is(err, ErrFingerprintMismatch) {
+        t.Errorf("other client: got %v, want ErrFingerprintMismatch", err)
+    }
+    if _, err := tk.Decrypt(refreshed, "key", DecryptOptions().Fingerprint([]byte("client-a"))).Into(&out); err != nil {
+        t.Errorf("same client: %v", err)
+    }
+}
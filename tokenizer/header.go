@@ -0,0 +1,90 @@
+package tokenizer
+
+import (
+	"time"
+)
+
+// Read-only view of a token header. Obtained without a key, the payload stays encrypted.
+//
+// Nothing in a header is authenticated until the token has been decrypted, so it
+// must only be used for routing, rate limiting or rejecting tokens early.
+type Header struct {
+    version     uint8
+    algorithm   TAlgorithm
+    identifier  Identifier
+    validFrom   time.Time
+    expiresIn   uint32
+    isValid     bool
+    validityErr error
+    kdf         KDF
+    keyID       *KeyID
+    tagLength   int
+    origin      time.Time
+    singleUse   bool
+    fingerprint bool
+}
+
+// Torken version of the token
+func (h *Header) Version() uint8          { return h.version }
+// Encryption algorithm
+func (h *Header) Algorithm() TAlgorithm   { return h.algorithm }
+// The identifier of the token, anonymous if there is none
+func (h *Header) Identifier() Identifier  { return h.identifier }
+// Time the token has been issued
+func (h *Header) ValidFrom() time.Time    { return h.validFrom }
+// Seconds the token takes to expire. If `0`, it never does.
+func (h *Header) ExpiresIn() uint32       { return h.expiresIn }
+// Whether the token is within its validity period
+func (h *Header) IsValid() bool           { return h.isValid }
+// Issue time of the first token of a refresh chain, ValidFrom if the token was never refreshed
+func (h *Header) Origin() time.Time       { return h.origin }
+// Whether the token can be decrypted only once
+func (h *Header) SingleUse() bool         { return h.singleUse }
+// Whether the token is bound to a client fingerprint
+func (h *Header) FingerprintBound() bool  { return h.fingerprint }
+// Why the token is not valid (ErrExpired or ErrNotYetValid), nil if it is
+func (h *Header) Err() error              { return h.validityErr }
+// Key derivation settings, KDF_NONE for raw keys
+func (h *Header) KDF() KDF                { return h.kdf }
+// MAC tag length in bytes (version >= 4), 0 for older tokens
+func (h *Header) TagLength() int          { return h.tagLength }
+// Key ring key id recorded in the token, if any
+func (h *Header) KeyID() (KeyID, bool) {
+    if h.keyID == nil {
+        return KeyID{}, false
+    }
+    return *h.keyID, true
+}
+
+// Parse the token header without a key. Strict mode does not apply here,
+// an expired token is reported through IsValid / Err.
+func (t *Tokenizer) Inspect(token string, options *decryptOptions) (*Header, error) {
+    I, err := t.int_inspect(t.config.Load(), token, options)
+    if err != nil {
+        return nil, err
+    }
+
+    ID, err := NewIdentifierFromBytes(I.Identifier)
+    if err != nil {
+        return nil, tkErr(STAGE_PARSE, err)
+    }
+
+    h := &Header{
+        version:     I.Version(),
+        algorithm:   I.Algorithm(),
+        identifier:  *ID,
+        validFrom:   time.Unix(int64(I.ValidFrom), 0),
+        expiresIn:   I.ExpiresIn,
+        isValid:     I.IsValid,
+        validityErr: I.ValidityErr,
+        kdf:         I.KDF,
+        keyID:       I.KeyID,
+        origin:      time.Unix(int64(I.issuedAt()), 0),
+        singleUse:   I.Flags&hfSINGLE_USE != 0,
+        fingerprint: I.Flags&hfFINGERPRINT != 0,
+    }
+    if I.Version() >= 4 {
+        h.tagLength = I.TagLength()
+    }
+    return h, nil
+}
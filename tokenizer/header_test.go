@@ -0,0 +1,93 @@
+package tokenizer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// A refreshed key ring token keeps key id, binding, KDF, tag length and origin in the header
+func TestInspectRefreshed(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    issued := now
+    tk := clockTokenizer(t, &now)
+    ring := NewKeyRing()
+    if err := ring.Add("2025", "secret"); err != nil {
+        t.Fatal(err)
+    }
+    keyID, _ := ring.KeyID("2025")
+    id := NewIdentifier()
+    fp := []byte("client-a")
+
+    token, err := tk.EncryptRing("session", ring, id, EncryptOptions().
+        ExpiresIn(600).
+        KDF(HKDF()).
+        TagLength(12).
+        Fingerprint(fp))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if h := inspect(t, tk, token); !h.Origin().Equal(h.ValidFrom()) {
+        t.Errorf("fresh token: origin %v, valid from %v", h.Origin(), h.ValidFrom())
+    }
+
+    now = now.Add(time.Minute)
+    refreshed, err := tk.Refresh(token, "secret", RefreshOptions().Fingerprint(fp))
+    if err != nil {
+        t.Fatal(err)
+    }
+    h := inspect(t, tk, refreshed)
+    if h.Version() != 4 || !h.IsValid() || h.ExpiresIn() != 600 {
+        t.Errorf("version %d, valid %v, expires in %d", h.Version(), h.IsValid(), h.ExpiresIn())
+    }
+    if got := h.Identifier(); !bytes.Equal(got.Bytes(), id.Bytes()) {
+        t.Errorf("identifier %s, want %s", got.Hex(), id.Hex())
+    }
+    if got, ok := h.KeyID(); !ok || got != keyID {
+        t.Errorf("key id %x (%v), want %x", got, ok, keyID)
+    }
+    if !h.ValidFrom().Equal(now) || !h.Origin().Equal(issued) {
+        t.Errorf("valid from %v, origin %v, want %v and %v", h.ValidFrom(), h.Origin(), now, issued)
+    }
+    if !h.FingerprintBound() || h.SingleUse() {
+        t.Errorf("fingerprint bound %v, single use %v", h.FingerprintBound(), h.SingleUse())
+    }
+    if h.KDF() != HKDF() || h.TagLength() != 12 {
+        t.Errorf("kdf %+v, tag length %d", h.KDF(), h.TagLength())
+    }
+}
+
+func TestInspectSingleUse(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    tk := singleUseTokenizer(t, &now, 0)
+    fp := []byte("client-a")
+
+    token, err := tk.Encrypt("once", "key", NewIdentifier(), EncryptOptions().ExpiresIn(60).SingleUse().Fingerprint(fp))
+    if err != nil {
+        t.Fatal(err)
+    }
+    h := inspect(t, tk, token)
+    if !h.SingleUse() || !h.FingerprintBound() {
+        t.Errorf("single use %v, fingerprint bound %v", h.SingleUse(), h.FingerprintBound())
+    }
+    if _, ok := h.KeyID(); ok {
+        t.Error("key id without key ring")
+    }
+    if h.TagLength() != cDEFAULT_TAG_LENGTH || h.KDF().Algorithm != KDF_NONE {
+        t.Errorf("tag length %d, kdf %+v", h.TagLength(), h.KDF())
+    }
+
+    // der Header bleibt auch nach dem Einlösen lesbar
+    var out string
+    if _, err := tk.Decrypt(token, "key", DecryptOptions().Fingerprint(fp)).Into(&out); err != nil {
+        t.Fatal(err)
+    }
+    if h := inspect(t, tk, token); !h.SingleUse() {
+        t.Error("single use flag lost after redeeming")
+    }
+
+    plain := inspect(t, tk, mustEncrypt(t, tk, nil))
+    if plain.SingleUse() || plain.FingerprintBound() {
+        t.Errorf("plain token: single use %v, fingerprint bound %v", plain.SingleUse(), plain.FingerprintBound())
+    }
+}
@@ -0,0 +1,201 @@
+package tokenizer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Key derivation function applied to the token key
+type KDFAlgorithm byte
+
+const (
+    KDF_NONE     KDFAlgorithm = 0 // raw key (zero padded / SHA256), legacy behaviour
+    KDF_HKDF     KDFAlgorithm = 1 // HKDF-SHA256, for high-entropy secrets
+    KDF_SCRYPT   KDFAlgorithm = 2 // scrypt, for passphrases
+    KDF_ARGON2ID KDFAlgorithm = 3 // Argon2id, for passphrases
+)
+
+// Default salt for all key derivations. It is fixed (and not random per token), so a
+// derived key can be cached and reused for every token of the same key. Deployments
+// using passphrases should set their own with TokenizerOptions().KDFSalt.
+const cKDF_SALT = "torken/kdf"
+
+// Key derivation settings. They are recorded in the token header (version >= 4).
+//   - KDF_SCRYPT:   P1 = log2(N), P2 = r, P3 = p
+//   - KDF_ARGON2ID: P1 = time, P2 = log2(memory in KiB), P3 = threads
+type KDF struct {
+    Algorithm  KDFAlgorithm
+    P1, P2, P3 uint8
+}
+
+// HKDF-SHA256 for keys that already carry enough entropy
+func HKDF() KDF {
+    return KDF{Algorithm: KDF_HKDF}
+}
+
+// scrypt with N = 2^logN
+func Scrypt(logN, r, p uint8) KDF {
+    return KDF{Algorithm: KDF_SCRYPT, P1: logN, P2: r, P3: p}
+}
+
+// scrypt with N = 2^15, r = 8, p = 1
+func ScryptDefault() KDF {
+    return Scrypt(15, 8, 1)
+}
+
+// Argon2id with 2^memoryLog2 KiB of memory
+func Argon2id(time, memoryLog2, threads uint8) KDF {
+    return KDF{Algorithm: KDF_ARGON2ID, P1: time, P2: memoryLog2, P3: threads}
+}
+
+// Argon2id with time = 1, 64 MiB and 4 threads
+func Argon2idDefault() KDF {
+    return Argon2id(1, 16, 4)
+}
+
+// Header representation: [algorithm][p1][p2][p3]
+func (k KDF) bytes() []byte {
+    return []byte{byte(k.Algorithm), k.P1, k.P2, k.P3}
+}
+
+func kdfFromBytes(b []byte) KDF {
+    return KDF{Algorithm: KDFAlgorithm(b[0]), P1: b[1], P2: b[2], P3: b[3]}
+}
+
+// Check the parameters. Parameters read from a token header are checked against
+// upper bounds, so a forged header can not make the verifier burn CPU or memory.
+func (k KDF) validate() error {
+    switch k.Algorithm {
+    case KDF_NONE, KDF_HKDF:
+        if k.P1 != 0 || k.P2 != 0 || k.P3 != 0 {
+            return wrapErr(ErrKeyDerivation, "unexpected parameters")
+        }
+    case KDF_SCRYPT:
+        if k.P1 < 10 || k.P1 > 20 || k.P2 < 1 || k.P2 > 32 || k.P3 < 1 || k.P3 > 16 {
+            return wrapErr(ErrKeyDerivation, "scrypt parameters out of bounds")
+        }
+    case KDF_ARGON2ID:
+        if k.P1 < 1 || k.P1 > 16 || k.P2 < 10 || k.P2 > 20 || k.P3 < 1 || k.P3 > 64 {
+            return wrapErr(ErrKeyDerivation, "argon2id parameters out of bounds")
+        }
+    default:
+        return wrapErr(ErrKeyDerivation, "unsupported algorithm")
+    }
+    return nil
+}
+
+// Passphrase KDFs, expensive enough that a forged header could burn CPU and memory
+func (k KDF) costly() bool {
+    return k.Algorithm == KDF_SCRYPT || k.Algorithm == KDF_ARGON2ID
+}
+
+// scrypt / Argon2id settings from the (not yet authenticated) token header are
+// only used if the caller pinned them (decryptOptions.KDF, compared in
+// int_inspect) or allowed them on the tokenizer.
+func (d *decryptIntermediate) checkKDF() error {
+    if !d.KDF.costly() {
+        return nil
+    }
+    if d.options != nil && d.options.kdf != nil {
+        return nil
+    }
+    if d.config != nil && slices.Contains(d.config.kdfs, d.KDF) {
+        return nil
+    }
+    return wrapErr(ErrKeyDerivation, "key derivation settings of the token have not been allowed")
+}
+
+func (k KDF) derive(key string, salt []byte) ([]byte, error) {
+    if len(salt) == 0 {
+        salt = []byte(cKDF_SALT)
+    }
+    switch k.Algorithm {
+    case KDF_HKDF:
+        out := make([]byte, 32)
+        if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(key), salt, []byte("torken/key")), out); err != nil {
+            return nil, err
+        }
+        return out, nil
+    case KDF_SCRYPT:
+        return scrypt.Key([]byte(key), salt, 1<<k.P1, int(k.P2), int(k.P3), 32)
+    case KDF_ARGON2ID:
+        return argon2.IDKey([]byte(key), salt, uint32(k.P1), 1<<k.P2, k.P3, 32), nil
+    }
+    return nil, wrapErr(ErrKeyDerivation, "unsupported algorithm")
+}
+
+// Bind a key to the purpose of a token (domain separation). The cipher and
+// the MAC both use the bound key, so a token opened for another purpose fails
+// integrity. Nothing is stored in the token. An empty purpose returns key unchanged.
+func purposeKey(key string, purpose string) string {
+    if len(purpose) == 0 {
+        return key
+    }
+    h := hmac.New(sha256.New, []byte(key))
+    h.Write([]byte("torken/purpose\x00"))
+    h.Write([]byte(purpose))
+    return string(h.Sum(nil))
+}
+
+// Upper bound for cached derived keys per tokenizer
+const cKDF_CACHE_SIZE = 256
+
+// Cache of derived keys, so the KDF cost is paid once per key and not once per token
+type kdfCache struct {
+    mu   sync.Mutex
+    keys map[string]string
+}
+
+func newKDFCache() *kdfCache {
+    return &kdfCache{keys: make(map[string]string)}
+}
+
+// Derive the cipher key for key with the given KDF and salt (empty: cKDF_SALT).
+// KDF_NONE returns key unchanged.
+func (c *kdfCache) derive(key string, k KDF, salt []byte) (string, error) {
+    if k.Algorithm == KDF_NONE {
+        return key, nil
+    }
+    if len(key) == 0 {
+        return "", errors.New("invalid parameters")
+    }
+    if err := k.validate(); err != nil {
+        return "", err
+    }
+    if c == nil {
+        out, err := k.derive(key, salt)
+        return string(out), err
+    }
+
+    // Salt mit Längenpräfix, damit Salt und Schlüssel nicht ineinander verschoben werden können
+    id := string(k.bytes()) + string(binary.LittleEndian.AppendUint32(nil, uint32(len(salt)))) + string(salt) + key
+    c.mu.Lock()
+    derived, ok := c.keys[id]
+    c.mu.Unlock()
+    if ok {
+        return derived, nil
+    }
+
+    out, err := k.derive(key, salt)
+    if err != nil {
+        return "", err
+    }
+    derived = string(out)
+
+    c.mu.Lock()
+    if len(c.keys) >= cKDF_CACHE_SIZE {
+        c.keys = make(map[string]string)
+    }
+    c.keys[id] = derived
+    c.mu.Unlock()
+    return derived, nil
+}
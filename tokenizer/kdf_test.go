@@ -0,0 +1,137 @@
+package tokenizer
+
+import (
+	"errors"
+	"testing"
+)
+
+// scrypt / Argon2id settings from the token header are not derived unless pinned or allowed
+func TestHeaderKDFNeedsPermission(t *testing.T) {
+    cheap := Scrypt(10, 1, 1)
+    tk := NewTokenizer()
+    token, err := tk.Encrypt("payload", "pass", nil, EncryptOptions().KDF(cheap))
+    if err != nil {
+        t.Fatal(err)
+    }
+    // Schlüssel des Encrypt-Aufrufs vergessen
+    tk.kdfCache = newKDFCache()
+
+    var out string
+    _, err = tk.Decrypt(token, "pass", nil).Into(&out)
+    if !errors.Is(err, ErrKeyDerivation) {
+        t.Fatalf("unpinned: got %v, want ErrKeyDerivation", err)
+    }
+    if n := len(tk.kdfCache.keys); n != 0 {
+        t.Fatalf("unpinned decrypt derived %d keys", n)
+    }
+
+    if _, err := tk.Decrypt(token, "pass", DecryptOptions().KDF(Scrypt(11, 1, 1))).Into(&out); !errors.Is(err, ErrKeyDerivation) {
+        t.Errorf("other pin: got %v, want ErrKeyDerivation", err)
+    }
+    if _, err := tk.Decrypt(token, "pass", DecryptOptions().KDF(cheap)).Into(&out); err != nil || out != "payload" {
+        t.Errorf("pinned: %v", err)
+    }
+
+    allowed, _ := NewTokenizerWith(TokenizerOptions().AllowKDF(cheap))
+    if _, err := allowed.Decrypt(token, "pass", nil).Into(&out); err != nil || out != "payload" {
+        t.Errorf("allowed: %v", err)
+    }
+    if _, err := allowed.Refresh(token, "pass", nil); err != nil {
+        t.Errorf("refresh allowed: %v", err)
+    }
+    if _, err := tk.Refresh(token, "pass", nil); !errors.Is(err, ErrKeyDerivation) {
+        t.Errorf("refresh unpinned: got %v, want ErrKeyDerivation", err)
+    }
+}
+
+// HKDF is cheap and taken from the header as before
+func TestHeaderHKDF(t *testing.T) {
+    tk := NewTokenizer()
+    token, err := tk.Encrypt("payload", "secret", nil, EncryptOptions().KDF(HKDF()))
+    if err != nil {
+        t.Fatal(err)
+    }
+    var out string
+    if _, err := tk.Decrypt(token, "secret", nil).Into(&out); err != nil || out != "payload" {
+        t.Errorf("hkdf: %v", err)
+    }
+}
+
+// Algorithm and version combinations with different integrity paths:
+// checksum (v1), MAC (ChaCha20 v3/v4) and AEAD (AES v2/v4, XChaCha20)
+var integrityVariants = []struct {
+    name    string
+    algo    TAlgorithm
+    version uint8
+}{
+    {"chacha20/v1", TALGO_CHACHA20, 1},
+    {"chacha20/v3", TALGO_CHACHA20, 3},
+    {"chacha20/v4", TALGO_CHACHA20, 4},
+    {"aes/v2", TALGO_AES, 2},
+    {"aes/v4", TALGO_AES, 4},
+    {"xchacha20/v2", TALGO_XCHACHA20, 2},
+    {"xchacha20/v4", TALGO_XCHACHA20, 4},
+}
+
+// A token opened for another purpose fails the integrity check
+func TestPurposeBinding(t *testing.T) {
+    tk := NewTokenizer()
+    for _, v := range integrityVariants {
+        token, err := tk.Encrypt("x", "key", nil, EncryptOptions().Algorithm(v.algo).Version(v.version).Purpose("session"))
+        if err != nil {
+            t.Fatalf("%s: %v", v.name, err)
+        }
+        var out string
+        if _, err := tk.Decrypt(token, "key", DecryptOptions().Purpose("session")).Into(&out); err != nil || out != "x" {
+            t.Errorf("%s, same purpose: %q, %v", v.name, out, err)
+        }
+        for _, other := range []string{"email-verify", ""} {
+            if _, err := tk.Decrypt(token, "key", DecryptOptions().Purpose(other)).Into(&out); !errors.Is(err, ErrIntegrity) {
+                t.Errorf("%s, purpose %q: got %v, want ErrIntegrity", v.name, other, err)
+            }
+        }
+
+        // ohne Purpose verschlüsselt, mit Purpose geöffnet
+        plain, err := tk.Encrypt("x", "key", nil, EncryptOptions().Algorithm(v.algo).Version(v.version))
+        if err != nil {
+            t.Fatalf("%s: %v", v.name, err)
+        }
+        if _, err := tk.Decrypt(plain, "key", DecryptOptions().Purpose("session")).Into(&out); !errors.Is(err, ErrIntegrity) {
+            t.Errorf("%s, unexpected purpose: got %v, want ErrIntegrity", v.name, err)
+        }
+    }
+}
+
+// The same passphrase gives other keys with another tokenizer salt
+func TestKDFSalt(t *testing.T) {
+    cheap := Scrypt(10, 1, 1)
+    a, _ := NewTokenizerWith(TokenizerOptions().AllowKDF(cheap).KDFSalt([]byte("deployment-a")))
+    b, _ := NewTokenizerWith(TokenizerOptions().AllowKDF(cheap).KDFSalt([]byte("deployment-b")))
+    plain, _ := NewTokenizerWith(TokenizerOptions().AllowKDF(cheap))
+
+    token, err := a.Encrypt("payload", "pass", nil, EncryptOptions().KDF(cheap))
+    if err != nil {
+        t.Fatal(err)
+    }
+    var out string
+    if _, err := a.Decrypt(token, "pass", nil).Into(&out); err != nil || out != "payload" {
+        t.Errorf("same salt: %v", err)
+    }
+    for name, tk := range map[string]*Tokenizer{"other salt": b, "default salt": plain} {
+        if _, err := tk.Decrypt(token, "pass", nil).Into(&out); !errors.Is(err, ErrIntegrity) {
+            t.Errorf("%s: got %v, want ErrIntegrity", name, err)
+        }
+    }
+
+    // der Cache unterscheidet nach Salt
+    cache := newKDFCache()
+    k1, _ := cache.derive("pass", cheap, []byte("deployment-a"))
+    k2, _ := cache.derive("pass", cheap, []byte("deployment-b"))
+    k3, _ := cache.derive("pass", cheap, nil)
+    if k1 == k2 || k1 == k3 || k2 == k3 {
+        t.Error("salt not part of the derived key")
+    }
+    if direct, _ := cheap.derive("pass", []byte(cKDF_SALT)); string(direct) != k3 {
+        t.Error("empty salt does not default to cKDF_SALT")
+    }
+}
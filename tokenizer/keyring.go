@@ -0,0 +1,172 @@
+package tokenizer
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Length of the key id recorded in the token header
+const cKEY_ID_LENGTH = 4
+
+// Short key id of a named key: the first 4 bytes of SHA256(name)
+type KeyID [cKEY_ID_LENGTH]byte
+
+func keyIDFor(name string) KeyID {
+    sum := sha256.Sum256([]byte(name))
+    var id KeyID
+    copy(id[:], sum[:cKEY_ID_LENGTH])
+    return id
+}
+
+type ringKey struct {
+    name    string
+    id      KeyID
+    key     string
+    retired bool
+    until   time.Time // retired keys decrypt until then
+}
+
+// KeyRing holds multiple named keys with one of them marked active.
+// Tokens are encrypted with the active key and record its key id, so
+// DecryptRing can pick the right key without guessing. Safe for concurrent use.
+type KeyRing struct {
+    mu     sync.RWMutex
+    byID   map[KeyID]*ringKey
+    byName map[string]*ringKey
+    active *ringKey
+}
+
+// New empty key ring
+func NewKeyRing() *KeyRing {
+    return &KeyRing{
+        byID:   make(map[KeyID]*ringKey),
+        byName: make(map[string]*ringKey),
+    }
+}
+
+// Add a named key. The first key added becomes the active one.
+func (r *KeyRing) Add(name string, key string) error {
+    if len(name) == 0 || len(key) == 0 {
+        return errors.New("key name and key must not be empty")
+    }
+    id := keyIDFor(name)
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if _, ok := r.byName[name]; ok {
+        return fmt.Errorf("key %q already exists", name)
+    }
+    if other, ok := r.byID[id]; ok {
+        return fmt.Errorf("key id of %q collides with %q", name, other.name)
+    }
+
+    k := &ringKey{name: name, id: id, key: key}
+    r.byID[id] = k
+    r.byName[name] = k
+    if r.active == nil {
+        r.active = k
+    }
+    return nil
+}
+
+// Mark a key as the active one, used for all new tokens
+func (r *KeyRing) SetActive(name string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    k, ok := r.byName[name]
+    if !ok {
+        return fmt.Errorf("unknown key %q", name)
+    }
+    k.retired = false
+    r.active = k
+    return nil
+}
+
+// Retire a key: tokens using it stay valid for decryption until the given time.
+// The active key can not be retired.
+func (r *KeyRing) Retire(name string, until time.Time) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    k, ok := r.byName[name]
+    if !ok {
+        return fmt.Errorf("unknown key %q", name)
+    }
+    if k == r.active {
+        return fmt.Errorf("key %q is active and can not be retired", name)
+    }
+    k.retired = true
+    k.until = until
+    return nil
+}
+
+// Remove a key from the ring. The active key can not be removed.
+func (r *KeyRing) Remove(name string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    k, ok := r.byName[name]
+    if !ok {
+        return nil
+    }
+    if k == r.active {
+        return fmt.Errorf("key %q is active and can not be removed", name)
+    }
+    delete(r.byName, name)
+    delete(r.byID, k.id)
+    return nil
+}
+
+// Name of the active key
+func (r *KeyRing) Active() (name string, ok bool) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    if r.active == nil {
+        return "", false
+    }
+    return r.active.name, true
+}
+
+// Key id of a named key
+func (r *KeyRing) KeyID(name string) (KeyID, bool) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    k, ok := r.byName[name]
+    if !ok {
+        return KeyID{}, false
+    }
+    return k.id, true
+}
+
+// Active key and its id for encryption
+func (r *KeyRing) activeKey() (string, KeyID, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    if r.active == nil {
+        return "", KeyID{}, errors.New("key ring has no active key")
+    }
+    return r.active.key, r.active.id, nil
+}
+
+// Key for decryption. Tokens without a key id fall back to the active key.
+func (r *KeyRing) resolve(id *KeyID, now time.Time) (string, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    if id == nil {
+        if r.active == nil {
+            return "", errors.New("key ring has no active key")
+        }
+        return r.active.key, nil
+    }
+
+    k, ok := r.byID[*id]
+    if !ok {
+        return "", fmt.Errorf("%w %x", ErrUnknownKey, id[:])
+    }
+    if k.retired && !now.Before(k.until) {
+        return "", fmt.Errorf("%w: %q", ErrKeyRetired, k.name)
+    }
+    return k.key, nil
+}
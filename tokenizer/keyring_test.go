@@ -0,0 +1,144 @@
+package tokenizer
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func decryptRing(tk *Tokenizer, token string, ring *KeyRing, options *decryptOptions) (string, error) {
+    var out string
+    _, err := tk.DecryptRing(token, ring, options).Into(&out)
+    return out, err
+}
+
+func TestKeyRingRotation(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    tk := clockTokenizer(t, &now)
+    ring := NewKeyRing()
+    if err := ring.Add("2024", "old-secret"); err != nil {
+        t.Fatal(err)
+    }
+    if err := ring.Add("2025", "new-secret"); err != nil {
+        t.Fatal(err)
+    }
+    if name, _ := ring.Active(); name != "2024" {
+        t.Fatalf("first key not active: %q", name)
+    }
+
+    old, err := tk.EncryptRing("old", ring, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := ring.SetActive("2025"); err != nil {
+        t.Fatal(err)
+    }
+    current, err := tk.EncryptRing("new", ring, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    want, _ := ring.KeyID("2025")
+    if id, ok := inspect(t, tk, current).KeyID(); !ok || id != want {
+        t.Errorf("key id %x, want %x", id, want)
+    }
+
+    // beide Schlüssel über die Key-ID
+    if out, err := decryptRing(tk, old, ring, nil); err != nil || out != "old" {
+        t.Errorf("old key: %q, %v", out, err)
+    }
+    if out, err := decryptRing(tk, current, ring, nil); err != nil || out != "new" {
+        t.Errorf("active key: %q, %v", out, err)
+    }
+
+    if err := ring.Retire("2025", now.Add(time.Hour)); err == nil {
+        t.Error("active key retired")
+    }
+    if err := ring.Retire("2024", now.Add(time.Hour)); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := decryptRing(tk, old, ring, nil); err != nil {
+        t.Errorf("retired, before until: %v", err)
+    }
+    now = now.Add(time.Hour)
+    if _, err := decryptRing(tk, old, ring, nil); !errors.Is(err, ErrKeyRetired) {
+        t.Errorf("retired, after until: got %v, want ErrKeyRetired", err)
+    }
+    // ValidateAt verschiebt nur die Gültigkeitsprüfung, nicht die Stilllegung
+    if _, err := decryptRing(tk, old, ring, DecryptOptions().ValidateAt(now.Add(-2*time.Hour))); !errors.Is(err, ErrKeyRetired) {
+        t.Errorf("retired, past ValidateAt: got %v, want ErrKeyRetired", err)
+    }
+
+    if err := ring.Remove("2025"); err == nil {
+        t.Error("active key removed")
+    }
+    if err := ring.Remove("2024"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := decryptRing(tk, old, ring, nil); !errors.Is(err, ErrUnknownKey) {
+        t.Errorf("removed: got %v, want ErrUnknownKey", err)
+    }
+}
+
+func TestKeyRingUnknownKeyID(t *testing.T) {
+    tk := NewTokenizer()
+    ring, other := NewKeyRing(), NewKeyRing()
+    ring.Add("a", "secret-a")
+    other.Add("b", "secret-b")
+
+    token, err := tk.EncryptRing("x", other, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := decryptRing(tk, token, ring, nil); !errors.Is(err, ErrUnknownKey) {
+        t.Errorf("got %v, want ErrUnknownKey", err)
+    }
+}
+
+// Tokens from Encrypt carry no key id and are decrypted with the active key
+func TestKeyRingWithoutKeyID(t *testing.T) {
+    tk := NewTokenizer()
+    ring := NewKeyRing()
+    ring.Add("legacy", "legacy-secret")
+    ring.Add("next", "next-secret")
+
+    token, err := tk.Encrypt("plain", "legacy-secret", nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if out, err := decryptRing(tk, token, ring, nil); err != nil || out != "plain" {
+        t.Errorf("active legacy: %q, %v", out, err)
+    }
+    ring.SetActive("next")
+    if _, err := decryptRing(tk, token, ring, nil); !errors.Is(err, ErrIntegrity) {
+        t.Errorf("active next: got %v, want ErrIntegrity", err)
+    }
+}
+
+func TestKeyRingAdd(t *testing.T) {
+    ring := NewKeyRing()
+    if _, _, err := ring.activeKey(); err == nil {
+        t.Error("empty ring has an active key")
+    }
+    if err := ring.Add("", "x"); err == nil {
+        t.Error("empty name accepted")
+    }
+    if err := ring.Add("a", ""); err == nil {
+        t.Error("empty key accepted")
+    }
+    if err := ring.Add("a", "x"); err != nil {
+        t.Fatal(err)
+    }
+    if err := ring.Add("a", "y"); err == nil {
+        t.Error("duplicate name accepted")
+    }
+    // gleiche ersten 4 Bytes von SHA256
+    if keyIDFor("key-8337") != keyIDFor("key-15029") {
+        t.Fatal("test names do not collide")
+    }
+    if err := ring.Add("key-8337", "x"); err != nil {
+        t.Fatal(err)
+    }
+    if err := ring.Add("key-15029", "y"); err == nil {
+        t.Error("colliding key id accepted")
+    }
+}
@@ -0,0 +1,305 @@
+package tokenizer
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Token layout (after unscrambling and baseX decoding):
+//
+//   [vhead][flags][identifier][kdf][keyid][origin][validFrom][expiresIn][integrity][salt][payload]
+//
+//   - flags:      only version >= 4, see hf* constants
+//   - identifier: only if vhead bit 7 is set
+//   - kdf:        key derivation settings (4 bytes), only if hfKDF is set
+//   - keyid:      key ring key id (4 bytes), only if hfKEY_ID is set
+//   - origin:     issue time of the first token of a refresh chain (4 bytes), only if hfORIGIN is set
+//   - integrity:  truncated SHA256 checksum (version <= 3) or keyed MAC (version >= 4);
+//                 XChaCha20 tokens below version 4 have none
+//   - salt:       nonce salt (ChaCha20 / AES, version >= 3; AES also in version 2) or the XChaCha20 nonce
+type tokenLayout struct {
+    flags      int
+    identifier int
+    kdf        int
+    keyID      int
+    origin     int
+    integrity  int
+    salt       int
+}
+
+// Header flags (version >= 4)
+const (
+    hfTAG_LENGTH_MASK byte = 0b111 // (tag length / 4) - 2, see tagLengthCode
+    hfKDF             byte = 1 << 3 // key derivation settings present
+    hfKEY_ID          byte = 1 << 4 // key ring key id present
+    hfORIGIN          byte = 1 << 5 // origin timestamp present (refreshed token)
+    hfSINGLE_USE      byte = 1 << 6 // token can be decrypted once, see ReplayCache
+    hfFINGERPRINT     byte = 1 << 7 // MAC covers a client fingerprint, see encryptOptions.Fingerprint
+
+    hfKNOWN_MASK = hfTAG_LENGTH_MASK | hfKDF | hfKEY_ID | hfORIGIN | hfSINGLE_USE | hfFINGERPRINT
+)
+
+const (
+    cMIN_TAG_LENGTH     = 8
+    cMAX_TAG_LENGTH     = 32
+    cDEFAULT_TAG_LENGTH = 16
+)
+
+// Encodes the MAC tag length into the header flags. Has to be a multiple of 4 in 8..32.
+func tagLengthCode(length int) (byte, error) {
+    if length < cMIN_TAG_LENGTH || length > cMAX_TAG_LENGTH || length%4 != 0 {
+        return 0, errors.New("tag length has to be a multiple of 4 between 8 and 32")
+    }
+    return byte(length/4 - 2), nil
+}
+
+// MAC tag length recorded in the header flags
+func (d *decryptIntermediate) TagLength() int {
+    return (int(d.Flags&hfTAG_LENGTH_MASK) + 2) * 4
+}
+
+func (d *decryptIntermediate) layout() tokenLayout {
+    L := tokenLayout{}
+    if d.Version() >= 4 {
+        L.flags = 1
+    }
+    if d.UsesIdentifier() {
+        L.identifier = cIDENTIFIER_LENGTH
+    }
+    if d.Flags&hfKDF != 0 {
+        L.kdf = 4
+    }
+    if d.Flags&hfKEY_ID != 0 {
+        L.keyID = cKEY_ID_LENGTH
+    }
+    if d.Flags&hfORIGIN != 0 {
+        L.origin = 4
+    }
+
+    switch {
+    case d.Version() >= 4:
+        L.integrity = d.TagLength()
+    case d.Algorithm() == TALGO_XCHACHA20:
+        L.integrity = 0
+    default:
+        L.integrity = 8
+    }
+
+    if d.Algorithm() == TALGO_XCHACHA20 {
+        L.salt = chacha20poly1305.NonceSizeX
+    } else if d.Version() >= 3 || (d.Version() == 2 && d.Algorithm() == TALGO_AES) {
+        // AES-GCM braucht ab Version 2 ein zufälliges IV
+        L.salt = cNONCE_SALT_LENGTH
+    }
+    return L
+}
+
+// Authenticated header fields (vhead + flags + identifier + kdf + keyid + origin + validFrom + expiresIn)
+func (d *decryptIntermediate) writeHeader(buf *bytes.Buffer) {
+    buf.WriteByte(d.Vhead)
+    if d.Version() >= 4 {
+        buf.WriteByte(d.Flags)
+    }
+    if d.UsesIdentifier() {
+        buf.Write(d.Identifier)
+    }
+    if d.Flags&hfKDF != 0 {
+        buf.Write(d.KDF.bytes())
+    }
+    if d.Flags&hfKEY_ID != 0 {
+        buf.Write(d.KeyID[:])
+    }
+    if d.Flags&hfORIGIN != 0 {
+        binary.Write(buf, binary.LittleEndian, d.Origin)
+    }
+    binary.Write(buf, binary.LittleEndian, d.ValidFrom)
+    binary.Write(buf, binary.LittleEndian, d.ExpiresIn)
+}
+
+// Fill the salt field with random bytes
+func (d *decryptIntermediate) randomizeSalt() error {
+    L := d.layout()
+    if L.salt == 0 {
+        return nil
+    }
+    d.Salt = make([]byte, L.salt)
+    _, err := rand.Read(d.Salt)
+    return err
+}
+
+// Associated data supplied by the caller, length prefixed so it can not be
+// shifted into the plaintext, followed by the fingerprint digest of bound tokens.
+// Writes nothing without either, so other tokens stay unchanged.
+func (d *decryptIntermediate) writeAAD(buf *bytes.Buffer) {
+    if len(d.AAD) > 0 {
+        binary.Write(buf, binary.LittleEndian, uint32(len(d.AAD)))
+        buf.Write(d.AAD)
+    }
+    if d.Flags&hfFINGERPRINT != 0 {
+        buf.Write(d.Fingerprint)
+    }
+}
+
+// Integrity value over the header, the associated data and the plaintext, nil if the layout has none.
+//   - version >= 4: HMAC-SHA256 keyed with a subkey of key, also covering the salt
+//   - otherwise:    unkeyed SHA256, truncated to 8 bytes
+func (d *decryptIntermediate) integrity(key string, plaintext []byte) []byte {
+    L := d.layout()
+    if L.integrity == 0 {
+        return nil
+    }
+
+    var input bytes.Buffer
+    d.writeHeader(&input)
+
+    out := make([]byte, L.integrity)
+    if d.Version() >= 4 {
+        input.Write(d.Salt)
+        d.writeAAD(&input)
+        input.Write(plaintext)
+        makeMAC(out, macKey(key), input.Bytes())
+    } else {
+        d.writeAAD(&input)
+        input.Write(plaintext)
+        makeChecksum(out, input.Bytes())
+    }
+    return out
+}
+
+// Constant-time comparison of the stored integrity value
+func (d *decryptIntermediate) verifyIntegrity(key string, plaintext []byte) bool {
+    expected := d.integrity(key, plaintext)
+    if expected == nil {
+        return true
+    }
+    return hmac.Equal(expected, d.Checksum)
+}
+
+// Nonce handed to the cipher.
+//   - XChaCha20: the 24 byte random salt
+//   - otherwise: validFrom + expiresIn + integrity[0:8] (4,4,8), salted from version 3 on
+func (d *decryptIntermediate) cipherNonce() []byte {
+    if d.Algorithm() == TALGO_XCHACHA20 {
+        return d.Salt
+    }
+    nonce := make([]byte, 16)
+    binary.LittleEndian.PutUint32(nonce[0:], d.ValidFrom)
+    binary.LittleEndian.PutUint32(nonce[4:], d.ExpiresIn)
+    copy(nonce[8:], d.Checksum)
+    if d.Salt != nil {
+        nonce = saltNonce(nonce, d.Salt)
+    }
+    return nonce
+}
+
+// Associated data for the AEAD algorithms. XChaCha20 always binds the header,
+// AES only from version 4 on (before that the checksum covers it). The caller's
+// associated data is bound in either case.
+func (d *decryptIntermediate) associatedData() []byte {
+    var ad bytes.Buffer
+    if d.Algorithm() == TALGO_XCHACHA20 || d.Version() >= 4 {
+        d.writeHeader(&ad)
+    }
+    d.writeAAD(&ad)
+    if ad.Len() == 0 {
+        return nil
+    }
+    return ad.Bytes()
+}
+
+func (d *decryptIntermediate) seal(key string, plaintext []byte) ([]byte, error) {
+    nonce := d.cipherNonce()
+    if isAEAD(d.Algorithm(), d.Version()) {
+        return aeadSeal(d.Algorithm(), plaintext, key, aeadNonce(d.Algorithm(), nonce), d.associatedData())
+    }
+    return crpEncrypt(d.Algorithm(), plaintext, key, nonce)
+}
+
+func (d *decryptIntermediate) open(key string) ([]byte, error) {
+    nonce := d.cipherNonce()
+    if isAEAD(d.Algorithm(), d.Version()) {
+        return aeadOpen(d.Algorithm(), d.EncryptedPayload, key, aeadNonce(d.Algorithm(), nonce), d.associatedData())
+    }
+    return crpDecrypt(d.Algorithm(), d.EncryptedPayload, key, nonce)
+}
+
+// Serialize the token fields as described by the layout
+func (d *decryptIntermediate) bytes() []byte {
+    var buf bytes.Buffer
+    d.writeHeader(&buf)
+    buf.Write(d.Checksum)
+    buf.Write(d.Salt)
+    buf.Write(d.EncryptedPayload)
+    return buf.Bytes()
+}
+
+// Parse the token fields as described by the layout
+func parseIntermediate(data []byte) (*decryptIntermediate, error) {
+    if len(data) < 1 {
+        return nil, wrapErr(ErrMalformed, "too short")
+    }
+
+    I := &decryptIntermediate{}
+    I.Vhead = data[0]
+
+    if I.Version() < 1 || I.Version() > cTOKENIZER_LATEST_VERSION {
+        return nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, I.Version())
+    }
+    if !algorithmSupported(I.Algorithm(), I.Version()) {
+        return nil, fmt.Errorf("%w %d for version %d", ErrUnsupportedAlgorithm, I.Algorithm(), I.Version())
+    }
+
+    if I.Version() >= 4 {
+        if len(data) < 2 {
+            return nil, wrapErr(ErrMalformed, "too short")
+        }
+        I.Flags = data[1]
+        if I.Flags & ^hfKNOWN_MASK != 0 || I.Flags&hfTAG_LENGTH_MASK > 6 {
+            return nil, fmt.Errorf("%w: unknown header flags %08b", ErrUnsupportedVersion, I.Flags)
+        }
+    }
+
+    L := I.layout()
+    minLen := 1 + L.flags + L.identifier + L.kdf + L.keyID + L.origin + 8 + L.integrity + L.salt
+    if len(data) < minLen {
+        return nil, wrapErr(ErrMalformed, "length too small")
+    }
+
+    pos := 1 + L.flags
+    take := func(n int) []byte {
+        if n == 0 {
+            return nil
+        }
+        out := append([]byte(nil), data[pos:pos+n]...)
+        pos += n
+        return out
+    }
+
+    I.Identifier = take(L.identifier)
+    if L.kdf > 0 {
+        I.KDF = kdfFromBytes(take(L.kdf))
+        if I.KDF.Algorithm == KDF_NONE {
+            return nil, wrapErr(ErrMalformed, "invalid key derivation")
+        }
+    }
+    if L.keyID > 0 {
+        I.KeyID = (*KeyID)(take(L.keyID))
+    }
+    if L.origin > 0 {
+        I.Origin = binary.LittleEndian.Uint32(take(L.origin))
+    }
+    I.ValidFrom = binary.LittleEndian.Uint32(take(4))
+    I.ExpiresIn = binary.LittleEndian.Uint32(take(4))
+    I.Checksum = take(L.integrity)
+    I.Salt = take(L.salt)
+    // rest = encryptedPayload
+    I.EncryptedPayload = append([]byte(nil), data[pos:]...)
+
+    return I, nil
+}
@@ -0,0 +1,107 @@
+package tokenizer
+
+import (
+	"errors"
+	"testing"
+)
+
+// Tokens with associated data only open with exactly the same data
+func TestAssociatedDataBinding(t *testing.T) {
+    tk := NewTokenizer()
+    for _, v := range integrityVariants {
+        token, err := tk.Encrypt("x", "key", nil, EncryptOptions().Algorithm(v.algo).Version(v.version).AssociatedData([]byte("tenant-a")))
+        if err != nil {
+            t.Fatalf("%s: %v", v.name, err)
+        }
+        var out string
+        if _, err := tk.Decrypt(token, "key", DecryptOptions().AssociatedData([]byte("tenant-a"))).Into(&out); err != nil || out != "x" {
+            t.Errorf("%s, same data: %q, %v", v.name, out, err)
+        }
+        for name, aad := range map[string][]byte{"other": []byte("tenant-b"), "prefix": []byte("tenant-"), "missing": nil} {
+            if _, err := tk.Decrypt(token, "key", DecryptOptions().AssociatedData(aad)).Into(&out); !errors.Is(err, ErrIntegrity) {
+                t.Errorf("%s, %s data: got %v, want ErrIntegrity", v.name, name, err)
+            }
+        }
+        if _, err := tk.Decrypt(token, "key", nil).Into(&out); !errors.Is(err, ErrIntegrity) {
+            t.Errorf("%s, no options: got %v, want ErrIntegrity", v.name, err)
+        }
+
+        plain, err := tk.Encrypt("x", "key", nil, EncryptOptions().Algorithm(v.algo).Version(v.version))
+        if err != nil {
+            t.Fatalf("%s: %v", v.name, err)
+        }
+        if _, err := tk.Decrypt(plain, "key", DecryptOptions().AssociatedData([]byte("tenant-a"))).Into(&out); !errors.Is(err, ErrIntegrity) {
+            t.Errorf("%s, unexpected data: got %v, want ErrIntegrity", v.name, err)
+        }
+    }
+}
+
+// v4 tag lengths: multiples of 4 from 8 to 32, recorded in the header flags
+func TestTagLength(t *testing.T) {
+    tk := NewTokenizer()
+    for length := cMIN_TAG_LENGTH; length <= cMAX_TAG_LENGTH; length += 4 {
+        for _, algo := range []TAlgorithm{TALGO_CHACHA20, TALGO_AES, TALGO_XCHACHA20} {
+            token, err := tk.Encrypt("x", "key", nil, EncryptOptions().Algorithm(algo).TagLength(length))
+            if err != nil {
+                t.Fatalf("tag length %d, algo %d: %v", length, algo, err)
+            }
+            h, err := tk.Inspect(token, nil)
+            if err != nil || h.TagLength() != length {
+                t.Errorf("tag length %d, algo %d: header %v, %v", length, algo, h, err)
+                continue
+            }
+            var out string
+            if _, err := tk.Decrypt(token, "key", nil).Into(&out); err != nil || out != "x" {
+                t.Errorf("tag length %d, algo %d: %q, %v", length, algo, out, err)
+            }
+        }
+    }
+    for _, length := range []int{-8, 0, 4, 7, 9, 30, 36, 64} {
+        if _, err := tk.Encrypt("x", "key", nil, EncryptOptions().TagLength(length)); err == nil {
+            t.Errorf("tag length %d accepted", length)
+        }
+    }
+    if _, err := tk.Encrypt("x", "key", nil, EncryptOptions().Version(3).TagLength(8)); err == nil {
+        t.Error("tag length accepted for version 3")
+    }
+    if h, _ := tk.Inspect(mustEncrypt(t, tk, EncryptOptions().Version(3)), nil); h.TagLength() != 0 {
+        t.Errorf("version 3 tag length %d, want 0", h.TagLength())
+    }
+}
+
+// The header code 7 (36 bytes) is out of range and rejected when parsing
+func TestTagLengthCodeOutOfRange(t *testing.T) {
+    tk := NewTokenizer()
+    for code := byte(0); code <= hfTAG_LENGTH_MASK; code++ {
+        I := &decryptIntermediate{Flags: code}
+        if _, err := tagLengthCode(I.TagLength()); (err == nil) != (code <= 6) {
+            t.Errorf("code %d: tag length %d, err %v", code, I.TagLength(), err)
+        }
+    }
+
+    // Code 6 im Header eines echten Tokens auf 7 setzen
+    c := tk.config.Load()
+    raw, err := c.baseX.Decode(mustEncrypt(t, tk, EncryptOptions().TagLength(32)))
+    if err != nil {
+        t.Fatal(err)
+    }
+    PseudoUnshuffle(raw, c.scramblerKey)
+    raw[1] |= hfTAG_LENGTH_MASK
+    PseudoShuffle(raw, c.scramblerKey)
+    forged, err := c.baseX.Encode(raw)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := tk.Inspect(forged, nil); !errors.Is(err, ErrUnsupportedVersion) {
+        t.Errorf("tag length code 7: got %v, want ErrUnsupportedVersion", err)
+    }
+}
+
+func mustEncrypt(t *testing.T, tk *Tokenizer, options *encryptOptions) string {
+    t.Helper()
+    token, err := tk.Encrypt("x", "key", nil, options)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return token
+}
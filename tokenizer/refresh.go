@@ -0,0 +1,127 @@
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Options for Tokenizer.Refresh
+type refreshOptions struct {
+    scrambler    *string
+    alphabet     *string
+    expiresIn    uint32
+    neverExpires bool
+    maxLifetime  time.Duration
+    allowExpired bool
+    purpose      string
+    aad          []byte
+    fingerprint  []byte
+    kdf          *KDF
+}
+// Scrambler of the old and the new token
+func (o *refreshOptions) Scrambler(v string)           *refreshOptions { o.scrambler = &v;    return o }
+// Alphabet of the old and the new token
+func (o *refreshOptions) Alphabet(v string)            *refreshOptions { o.alphabet = &v;     return o }
+// Lifetime of the new token in seconds, counted from now. If `0` (default), the old token's lifetime is kept.
+func (o *refreshOptions) ExpiresIn(v uint32)           *refreshOptions { o.expiresIn = v; o.neverExpires = false; return o }
+// Issue the new token without expiry, only capped by MaxLifetime
+func (o *refreshOptions) NeverExpires()                *refreshOptions { o.neverExpires = true; o.expiresIn = 0; return o }
+// Cap the lifetime of the whole refresh chain, counted from the first token's issue time
+func (o *refreshOptions) MaxLifetime(v time.Duration)  *refreshOptions { o.maxLifetime = v;   return o }
+// Also refresh tokens that are expired or not yet valid. Off by default.
+func (o *refreshOptions) AllowExpired(v bool)          *refreshOptions { o.allowExpired = v;  return o }
+// Purpose of the old and the new token
+func (o *refreshOptions) Purpose(v string)             *refreshOptions { o.purpose = v;       return o }
+// Associated data of the old and the new token
+func (o *refreshOptions) AssociatedData(v []byte)      *refreshOptions { o.aad = v;           return o }
+// Client fingerprint of a bound token. The new token stays bound to it.
+func (o *refreshOptions) Fingerprint(v []byte)         *refreshOptions { o.fingerprint = v;   return o }
+// Key derivation of the old token, see decryptOptions.KDF
+func (o *refreshOptions) KDF(v KDF)                    *refreshOptions { o.kdf = &v;          return o }
+
+func RefreshOptions() *refreshOptions {
+    return &refreshOptions{}
+}
+
+// Issue time of the first token of a refresh chain
+func (d *decryptIntermediate) issuedAt() uint32 {
+    if d.Flags&hfORIGIN != 0 {
+        return d.Origin
+    }
+    return d.ValidFrom
+}
+
+// Re-issue a token: the old token is decrypted and verified, its identifier,
+// serialized payload, algorithm, key derivation, key id, purpose, fingerprint binding and lifetime are kept,
+// validFrom starts over. The new token always uses the latest token version. The new token records the issue time of the first
+// token, so MaxLifetime holds across any number of refreshes.
+func (t *Tokenizer) Refresh(token, key string, options *refreshOptions) (string, error) {
+    if options == nil {
+        options = RefreshOptions()
+    }
+    c := t.config.Load()
+
+    dopts := DecryptOptions()
+    dopts.scrambler = options.scrambler
+    dopts.alphabet = options.alphabet
+    dopts.purpose = options.purpose
+    dopts.aad = options.aad
+    dopts.fingerprint = options.fingerprint
+    dopts.kdf = options.kdf
+
+    I, err := t.int_inspect(c, token, dopts)
+    if err != nil {
+        return "", err
+    }
+    if !I.IsValid && !options.allowExpired {
+        return "", I.ValidityErr
+    }
+    if I.Flags&hfSINGLE_USE != 0 {
+        return "", tkErr(STAGE_VALIDATE, errors.New("single-use tokens can not be refreshed"))
+    }
+    payload, err := t.int_decrypt_finalize(I, key)
+    if err != nil {
+        return "", err
+    }
+
+    now := c.now().Unix()
+    origin := I.issuedAt()
+    expiresIn := int64(I.ExpiresIn)
+    if options.expiresIn > 0 {
+        expiresIn = int64(options.expiresIn)
+    } else if options.neverExpires {
+        expiresIn = 0
+    }
+    if options.maxLifetime > 0 {
+        remaining := int64(origin) + int64(options.maxLifetime/time.Second) - now
+        if remaining <= 0 {
+            return "", tkErr(STAGE_VALIDATE, fmt.Errorf("%w: issued %d", ErrLifetimeExceeded, origin))
+        }
+        if expiresIn == 0 || expiresIn > remaining {
+            expiresIn = remaining
+        }
+    }
+
+    eopts := EncryptOptions().
+        ValidFromTS(uint32(now)).
+        ExpiresIn(uint32(expiresIn)).
+        Algorithm(I.Algorithm())
+    eopts.scrambler = options.scrambler
+    eopts.alphabet = options.alphabet
+    eopts.purpose = options.purpose
+    eopts.aad = options.aad
+    eopts.keyID = I.KeyID // gleicher Schlüssel, gleiche Key-ID
+    eopts.origin = &origin
+    if I.KDF.Algorithm != KDF_NONE {
+        eopts.KDF(I.KDF)
+    }
+    if I.Version() >= 4 {
+        eopts.TagLength(I.TagLength())
+    }
+    if I.Flags&hfFINGERPRINT != 0 {
+        eopts.Fingerprint(options.fingerprint)
+    }
+
+    return t.int_encrypt(I.Identifier, key, payload, eopts)
+}
@@ -0,0 +1,145 @@
+package tokenizer
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func clockTokenizer(t *testing.T, now *time.Time) *Tokenizer {
+    t.Helper()
+    tk, err := NewTokenizerWith(TokenizerOptions().Clock(ClockFunc(func() time.Time { return *now })))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return tk
+}
+
+func inspect(t *testing.T, tk *Tokenizer, token string) *Header {
+    t.Helper()
+    h, err := tk.Inspect(token, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return h
+}
+
+// Without ExpiresIn the refreshed token keeps the old lifetime
+func TestRefreshKeepsLifetime(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    tk := clockTokenizer(t, &now)
+    token, err := tk.Encrypt("session", "key", NewIdentifier(), EncryptOptions().ExpiresIn(60))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    now = now.Add(30 * time.Second)
+    refreshed, err := tk.Refresh(token, "key", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if h := inspect(t, tk, refreshed); h.ExpiresIn() != 60 || !h.ValidFrom().Equal(now) {
+        t.Errorf("default: expires in %d from %v, want 60 from %v", h.ExpiresIn(), h.ValidFrom(), now)
+    }
+
+    refreshed, err = tk.Refresh(token, "key", RefreshOptions().ExpiresIn(300))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if h := inspect(t, tk, refreshed); h.ExpiresIn() != 300 {
+        t.Errorf("ExpiresIn(300): expires in %d", h.ExpiresIn())
+    }
+
+    refreshed, err = tk.Refresh(token, "key", RefreshOptions().NeverExpires())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if h := inspect(t, tk, refreshed); h.ExpiresIn() != 0 {
+        t.Errorf("NeverExpires: expires in %d", h.ExpiresIn())
+    }
+}
+
+// MaxLifetime holds across a chain of refreshes, counted from the first token
+func TestRefreshMaxLifetime(t *testing.T) {
+    issued := time.Unix(1700000000, 0)
+    now := issued
+    tk := clockTokenizer(t, &now)
+    token, err := tk.Encrypt("session", "key", NewIdentifier(), EncryptOptions().ExpiresIn(60))
+    if err != nil {
+        t.Fatal(err)
+    }
+    ropts := RefreshOptions().MaxLifetime(100 * time.Second)
+
+    for _, step := range []struct {
+        at      time.Duration
+        expires uint32
+    }{
+        {50 * time.Second, 50}, // min(60, 100-50)
+        {90 * time.Second, 10},
+        {99 * time.Second, 1},
+    } {
+        now = issued.Add(step.at)
+        token, err = tk.Refresh(token, "key", ropts)
+        if err != nil {
+            t.Fatalf("refresh at +%v: %v", step.at, err)
+        }
+        h := inspect(t, tk, token)
+        if h.ExpiresIn() != step.expires {
+            t.Errorf("refresh at +%v: expires in %d, want %d", step.at, h.ExpiresIn(), step.expires)
+        }
+        if !h.Origin().Equal(issued) {
+            t.Errorf("refresh at +%v: origin %v, want %v", step.at, h.Origin(), issued)
+        }
+    }
+
+    now = issued.Add(100 * time.Second)
+    if _, err := tk.Refresh(token, "key", RefreshOptions().MaxLifetime(100*time.Second).AllowExpired(true)); !errors.Is(err, ErrLifetimeExceeded) {
+        t.Errorf("past max lifetime: got %v, want ErrLifetimeExceeded", err)
+    }
+    // NeverExpires ist ebenfalls begrenzt
+    now = issued.Add(99 * time.Second)
+    if _, err := tk.Refresh(token, "key", RefreshOptions().MaxLifetime(200*time.Second).NeverExpires()); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestRefreshAllowExpired(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    tk := clockTokenizer(t, &now)
+    token, err := tk.Encrypt("session", "key", NewIdentifier(), EncryptOptions().ExpiresIn(60))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    now = now.Add(2 * time.Minute)
+    if _, err := tk.Refresh(token, "key", nil); !errors.Is(err, ErrExpired) {
+        t.Errorf("expired: got %v, want ErrExpired", err)
+    }
+    refreshed, err := tk.Refresh(token, "key", RefreshOptions().AllowExpired(true))
+    if err != nil {
+        t.Fatalf("AllowExpired: %v", err)
+    }
+    var out string
+    if res, err := tk.Decrypt(refreshed, "key", nil).Into(&out); err != nil || !res.IsValid() || out != "session" {
+        t.Errorf("refreshed: %q, %v", out, err)
+    }
+}
+
+func TestRefreshRefusesSingleUse(t *testing.T) {
+    tk, err := NewTokenizerWith(TokenizerOptions().ReplayCache(NewMemoryReplayCache(nil)))
+    if err != nil {
+        t.Fatal(err)
+    }
+    token, err := tk.Encrypt("once", "key", NewIdentifier(), EncryptOptions().ExpiresIn(60).SingleUse())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := tk.Refresh(token, "key", nil); err == nil {
+        t.Error("single-use token refreshed")
+    }
+    // nicht verbraucht
+    var out string
+    if _, err := tk.Decrypt(token, "key", nil).Into(&out); err != nil {
+        t.Errorf("decrypt after refused refresh: %v", err)
+    }
+}
@@ -0,0 +1,79 @@
+package tokenizer
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Records single-use tokens. A token is identified by its identifier and its
+// random salt, which is unique per token.
+type ReplayCache interface {
+    // Record key until the given time and report whether it had been recorded before.
+    // Has to be atomic, two concurrent calls for the same key must not both return false.
+    Seen(key []byte, until time.Time) (bool, error)
+}
+
+// Replay check of a verified token. Runs last (after the claims checks in Into),
+// so tokens rejected for any other reason are not used up.
+func (d *decryptIntermediate) checkReplay() error {
+    if d.Flags&hfSINGLE_USE == 0 {
+        return nil
+    }
+    // fail closed
+    if d.config == nil || d.config.replays == nil {
+        return errors.New("single-use token but no replay cache configured")
+    }
+    // immer strikt und zur echten Zeit (nicht ValidateAt), mit höchstens
+    // maxLeeway, sonst könnte ein Aufruf nach dem Verwerfen des Eintrags den Replay erlauben
+    leeway := min(d.Leeway, d.config.maxLeeway)
+    if err := d.validity(d.config.now(), leeway); err != nil {
+        return err
+    }
+
+    key := make([]byte, 0, len(d.Identifier)+len(d.Salt))
+    key = append(key, d.Identifier...)
+    key = append(key, d.Salt...)
+    // solange aufheben, wie das Token mit irgendeiner erlaubten Leeway gültig sein kann
+    until := time.Unix(int64(d.ValidFrom)+int64(d.ExpiresIn), 0).Add(d.config.maxLeeway)
+
+    seen, err := d.config.replays.Seen(key, until)
+    if err != nil {
+        return err
+    }
+    if seen {
+        return ErrReplayed
+    }
+    return nil
+}
+
+// In-memory replay cache. Entries are dropped once their token has expired.
+type MemoryReplayCache struct {
+    mu      sync.Mutex
+    clock   Clock
+    entries expiringSet
+}
+
+// New in-memory replay cache. A nil clock uses the system clock.
+func NewMemoryReplayCache(clock Clock) *MemoryReplayCache {
+    if clock == nil {
+        clock = SystemClock()
+    }
+    return &MemoryReplayCache{
+        clock:   clock,
+        entries: newExpiringSet(),
+    }
+}
+
+func (c *MemoryReplayCache) Seen(key []byte, until time.Time) (bool, error) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    now := c.clock.Now()
+    c.entries.sweep(now)
+
+    if c.entries.live(string(key), now) {
+        return true, nil
+    }
+    c.entries.m[string(key)] = until
+    return false, nil
+}
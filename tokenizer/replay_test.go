@@ -0,0 +1,86 @@
+package tokenizer
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func singleUseTokenizer(t *testing.T, now *time.Time, maxLeeway time.Duration) *Tokenizer {
+    t.Helper()
+    clock := ClockFunc(func() time.Time { return *now })
+    tk, err := NewTokenizerWith(TokenizerOptions().
+        Clock(clock).
+        ReplayCache(NewMemoryReplayCache(clock)).
+        MaxLeeway(maxLeeway))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return tk
+}
+
+// A larger per-call leeway must not redeem a token again after expiry
+func TestReplayLeewayIsCapped(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    tk := singleUseTokenizer(t, &now, time.Minute)
+    token, err := tk.Encrypt("once", "key", NewIdentifier(), EncryptOptions().ExpiresIn(60).SingleUse())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var out string
+    if _, err := tk.Decrypt(token, "key", nil).Into(&out); err != nil {
+        t.Fatalf("first use: %v", err)
+    }
+
+    // 30s nach Ablauf: innerhalb von maxLeeway, Eintrag muss noch da sein
+    now = now.Add(90 * time.Second)
+    if _, err := tk.Decrypt(token, "key", DecryptOptions().Leeway(time.Hour)).Into(&out); !errors.Is(err, ErrReplayed) {
+        t.Errorf("within max leeway: got %v, want ErrReplayed", err)
+    }
+
+    // 10min nach Ablauf: Eintrag darf weg sein, aber die Leeway ist auf maxLeeway begrenzt
+    now = now.Add(10 * time.Minute)
+    if _, err := tk.Decrypt(token, "key", DecryptOptions().Leeway(time.Hour)).Into(&out); !errors.Is(err, ErrExpired) {
+        t.Errorf("past max leeway: got %v, want ErrExpired", err)
+    }
+}
+
+// ValidateAt must not let an expired single-use token through
+func TestReplayIgnoresValidateAt(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    tk := singleUseTokenizer(t, &now, 0)
+    token, err := tk.Encrypt("once", "key", NewIdentifier(), EncryptOptions().ExpiresIn(60).SingleUse())
+    if err != nil {
+        t.Fatal(err)
+    }
+    issued := now
+    now = now.Add(time.Hour)
+
+    var out string
+    _, err = tk.Decrypt(token, "key", DecryptOptions().Strict(false).ValidateAt(issued)).Into(&out)
+    if !errors.Is(err, ErrExpired) {
+        t.Errorf("got %v, want ErrExpired", err)
+    }
+}
+
+// A token failing the claims checks is not used up
+func TestReplayAfterClaims(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    tk := singleUseTokenizer(t, &now, 0)
+    token, err := tk.Encrypt(StandardClaims{Issuer: "auth"}, "key", NewIdentifier(), EncryptOptions().ExpiresIn(60).SingleUse())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var out StandardClaims
+    if _, err := tk.Decrypt(token, "key", DecryptOptions().ExpectIssuer("other")).Into(&out); !errors.Is(err, ErrIssuerMismatch) {
+        t.Fatalf("wrong issuer: got %v, want ErrIssuerMismatch", err)
+    }
+    if _, err := tk.Decrypt(token, "key", DecryptOptions().ExpectIssuer("auth")).Into(&out); err != nil {
+        t.Fatalf("first valid use: %v", err)
+    }
+    if _, err := tk.Decrypt(token, "key", nil).Into(&out); !errors.Is(err, ErrReplayed) {
+        t.Errorf("second use: got %v, want ErrReplayed", err)
+    }
+}
@@ -0,0 +1,172 @@
+package tokenizer
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Revocation list keyed by token identifier. It is consulted after a token has
+// been decrypted and verified, so forged headers can not probe it.
+// Anonymous tokens can not be revoked.
+type Revoker interface {
+    // Revoke every token carrying the identifier
+    Revoke(id Identifier) error
+    IsRevoked(id Identifier) (bool, error)
+}
+
+// Revocation check of a verified token
+func (d *decryptIntermediate) checkRevoked() error {
+    if d.config == nil || d.config.revoker == nil || !d.UsesIdentifier() {
+        return nil
+    }
+    id, err := NewIdentifierFromBytes(d.Identifier)
+    if err != nil {
+        return err
+    }
+    revoked, err := d.config.revoker.IsRevoked(*id)
+    if err != nil {
+        return err
+    }
+    if revoked {
+        return fmt.Errorf("%w: %s", ErrRevoked, id.Hex())
+    }
+    return nil
+}
+
+// In-memory revocation list. An entry is dropped once maxLifetime has passed
+// after the revocation, as every token it could match has expired by then.
+// maxLifetime has to be at least the longest token lifetime; 0 keeps entries forever.
+type MemoryRevoker struct {
+    mu          sync.Mutex
+    maxLifetime time.Duration
+    clock       Clock
+    entries     expiringSet // identifier => drop after (zero: never)
+}
+
+// New in-memory revoker. A nil clock uses the system clock.
+func NewMemoryRevoker(maxLifetime time.Duration, clock Clock) *MemoryRevoker {
+    if clock == nil {
+        clock = SystemClock()
+    }
+    return &MemoryRevoker{
+        maxLifetime: maxLifetime,
+        clock:       clock,
+        entries:     newExpiringSet(),
+    }
+}
+
+func (r *MemoryRevoker) Revoke(id Identifier) error {
+    if id.Anonymous() {
+        return errors.New("anonymous tokens can not be revoked")
+    }
+    r.add(string(id.Bytes()), r.dropAfter())
+    return nil
+}
+
+func (r *MemoryRevoker) IsRevoked(id Identifier) (bool, error) {
+    if id.Anonymous() {
+        return false, nil
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.entries.live(string(id.Bytes()), r.clock.Now()), nil
+}
+
+func (r *MemoryRevoker) dropAfter() time.Time {
+    if r.maxLifetime <= 0 {
+        return time.Time{}
+    }
+    return r.clock.Now().Add(r.maxLifetime)
+}
+
+func (r *MemoryRevoker) add(id string, until time.Time) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.entries.m[id] = until
+    r.entries.sweep(r.clock.Now())
+}
+
+// File-backed revocation list that survives restarts. Revocations are appended
+// to the file as "<identifier hex> <drop after unix>" lines, expired lines are
+// removed when the file is opened.
+type FileRevoker struct {
+    mem *MemoryRevoker
+    log *fileLog
+}
+
+// Open or create the revocation file at path. A nil clock uses the system clock.
+func NewFileRevoker(path string, maxLifetime time.Duration, clock Clock) (*FileRevoker, error) {
+    mem := NewMemoryRevoker(maxLifetime, clock)
+    now := mem.clock.Now()
+
+    log, err := openFileLog(path, func(line string) {
+        id, until, ok := parseRevocation(line)
+        if ok && (until.IsZero() || now.Before(until)) {
+            mem.entries.m[id] = until
+        }
+    }, func() []string {
+        lines := make([]string, 0, len(mem.entries.m))
+        for id, until := range mem.entries.m {
+            lines = append(lines, formatRevocation(id, until))
+        }
+        return lines
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &FileRevoker{mem: mem, log: log}, nil
+}
+
+func (r *FileRevoker) Revoke(id Identifier) error {
+    if id.Anonymous() {
+        return errors.New("anonymous tokens can not be revoked")
+    }
+    until := r.mem.dropAfter()
+    if err := r.log.append(formatRevocation(string(id.Bytes()), until)); err != nil {
+        return err
+    }
+    r.mem.add(string(id.Bytes()), until)
+    return nil
+}
+
+func (r *FileRevoker) IsRevoked(id Identifier) (bool, error) {
+    return r.mem.IsRevoked(id)
+}
+
+// Close the underlying file
+func (r *FileRevoker) Close() error {
+    return r.log.close()
+}
+
+func formatRevocation(id string, until time.Time) string {
+    var ts int64
+    if !until.IsZero() {
+        ts = until.Unix()
+    }
+    return fmt.Sprintf("%s %d", hex.EncodeToString([]byte(id)), ts)
+}
+
+func parseRevocation(line string) (string, time.Time, bool) {
+    hx, ts, found := strings.Cut(line, " ")
+    if !found {
+        return "", time.Time{}, false
+    }
+    id, err := hex.DecodeString(hx)
+    if err != nil || len(id) != cIDENTIFIER_LENGTH {
+        return "", time.Time{}, false
+    }
+    unix, err := strconv.ParseInt(ts, 10, 64)
+    if err != nil {
+        return "", time.Time{}, false
+    }
+    var until time.Time
+    if unix != 0 {
+        until = time.Unix(unix, 0)
+    }
+    return string(id), until, true
+}
@@ -0,0 +1,141 @@
+package tokenizer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryRevokerMaxLifetime(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    clock := ClockFunc(func() time.Time { return now })
+    r := NewMemoryRevoker(time.Hour, clock)
+    id := NewIdentifier()
+
+    if err := r.Revoke(*NewIdentifierAnonymous()); err == nil {
+        t.Error("anonymous identifier revoked")
+    }
+    if err := r.Revoke(*id); err != nil {
+        t.Fatal(err)
+    }
+    if revoked, _ := r.IsRevoked(*id); !revoked {
+        t.Error("not revoked")
+    }
+    if revoked, _ := r.IsRevoked(*NewIdentifier()); revoked {
+        t.Error("other identifier revoked")
+    }
+
+    now = now.Add(time.Hour)
+    if revoked, _ := r.IsRevoked(*id); revoked {
+        t.Error("still revoked after maxLifetime")
+    }
+    // der nächste Eintrag räumt auf
+    r.Revoke(*NewIdentifier())
+    if _, ok := r.entries.m[string(id.Bytes())]; ok {
+        t.Error("expired entry not swept")
+    }
+
+    forever := NewMemoryRevoker(0, clock)
+    forever.Revoke(*id)
+    now = now.Add(100 * 365 * 24 * time.Hour)
+    if revoked, _ := forever.IsRevoked(*id); !revoked {
+        t.Error("maxLifetime 0: entry dropped")
+    }
+}
+
+func TestRevokedTokenRejected(t *testing.T) {
+    r := NewMemoryRevoker(0, nil)
+    tk, err := NewTokenizerWith(TokenizerOptions().Revoker(r))
+    if err != nil {
+        t.Fatal(err)
+    }
+    id := NewIdentifier()
+    token, err := tk.Encrypt("x", "key", id, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var out string
+    if _, err := tk.Decrypt(token, "key", nil).Into(&out); err != nil {
+        t.Fatal(err)
+    }
+    r.Revoke(*id)
+    if _, err := tk.Decrypt(token, "key", nil).Into(&out); !errors.Is(err, ErrRevoked) {
+        t.Errorf("got %v, want ErrRevoked", err)
+    }
+}
+
+func TestFileRevokerReopen(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    clock := ClockFunc(func() time.Time { return now })
+    path := filepath.Join(t.TempDir(), "revoked")
+
+    r, err := NewFileRevoker(path, time.Hour, clock)
+    if err != nil {
+        t.Fatal(err)
+    }
+    short, long := NewIdentifier(), NewIdentifier()
+    if err := r.Revoke(*short); err != nil {
+        t.Fatal(err)
+    }
+    now = now.Add(30 * time.Minute)
+    if err := r.Revoke(*long); err != nil {
+        t.Fatal(err)
+    }
+    r.Close()
+    if err := r.Revoke(*NewIdentifier()); err == nil {
+        t.Error("revoke after close")
+    }
+
+    r, err = NewFileRevoker(path, time.Hour, clock)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, id := range []*Identifier{short, long} {
+        if revoked, _ := r.IsRevoked(*id); !revoked {
+            t.Errorf("%s not revoked after reopen", id.Hex())
+        }
+    }
+    r.Close()
+
+    // short ist abgelaufen und fällt beim Öffnen aus der Datei
+    now = now.Add(45 * time.Minute)
+    r, err = NewFileRevoker(path, time.Hour, clock)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer r.Close()
+    if revoked, _ := r.IsRevoked(*short); revoked {
+        t.Error("expired revocation loaded")
+    }
+    if revoked, _ := r.IsRevoked(*long); !revoked {
+        t.Error("live revocation lost")
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 1 || !strings.HasPrefix(lines[0], long.Hex()+" ") {
+        t.Errorf("compacted file: %q", data)
+    }
+}
+
+// Broken lines are skipped instead of failing the open
+func TestFileRevokerSkipsBrokenLines(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "revoked")
+    id := NewIdentifier()
+    content := "# comment\nnot-hex 0\n" + id.Hex() + " x\n" + id.Hex() + " 0\n\n"
+    if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    r, err := NewFileRevoker(path, 0, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer r.Close()
+    if revoked, _ := r.IsRevoked(*id); !revoked {
+        t.Error("valid line not loaded")
+    }
+}
@@ -0,0 +1,86 @@
+package tokenizer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Separator between the levels of a hierarchical scope like "orders:items:read"
+const cSCOPE_SEPARATOR = ":"
+
+// Scopes granted to a token. A granted scope matches a required one if it is
+// equal, or level by level with "*" standing for any single level. A trailing
+// "*" matches all remaining levels, so "orders:*" grants "orders:read" as well
+// as "orders:items:read", and "*" grants everything. Wildcards only count in granted
+// scopes, and an empty scope neither grants nor is granted.
+type Scopes []string
+
+// Whether scope is granted
+func (s Scopes) HasScope(scope string) bool {
+    for _, granted := range s {
+        if scopeMatches(granted, scope) {
+            return true
+        }
+    }
+    return false
+}
+
+// Whether every one of the scopes is granted
+func (s Scopes) HasAll(scopes ...string) bool {
+    for _, scope := range scopes {
+        if !s.HasScope(scope) {
+            return false
+        }
+    }
+    return true
+}
+
+// Whether at least one of the scopes is granted
+func (s Scopes) HasAny(scopes ...string) bool {
+    for _, scope := range scopes {
+        if s.HasScope(scope) {
+            return true
+        }
+    }
+    return false
+}
+
+// Required scopes that are not granted
+func (s Scopes) Missing(scopes ...string) []string {
+    var missing []string
+    for _, scope := range scopes {
+        if !s.HasScope(scope) {
+            missing = append(missing, scope)
+        }
+    }
+    return missing
+}
+
+func scopeMatches(granted, required string) bool {
+    if len(granted) == 0 || len(required) == 0 {
+        return false
+    }
+    if granted == required {
+        return true
+    }
+    g := strings.Split(granted, cSCOPE_SEPARATOR)
+    r := strings.Split(required, cSCOPE_SEPARATOR)
+    for i, level := range g {
+        if level == "*" && i == len(g)-1 {
+            // trailing wildcard needs at least one level left
+            return len(r) > i
+        }
+        if i >= len(r) || (level != "*" && level != r[i]) {
+            return false
+        }
+    }
+    return len(g) == len(r)
+}
+
+// Check the scopes required by the decrypt options
+func (o *decryptOptions) verifyScopes(c *StandardClaims) error {
+    if missing := c.Scope.Missing(o.scopes...); len(missing) > 0 {
+        return fmt.Errorf("%w: missing %q", ErrInsufficientScope, missing)
+    }
+    return nil
+}
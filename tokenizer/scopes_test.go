@@ -0,0 +1,56 @@
+package tokenizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScopeMatches(t *testing.T) {
+    cases := []struct {
+        granted, required string
+        want              bool
+    }{
+        {"orders", "orders", true},
+        {"orders", "orders:read", false},
+        {"orders:read", "orders", false},
+        {"orders:*", "orders", false},
+        {"orders:*", "orders:read", true},
+        {"orders:*", "orders:a:b", true},
+        {"orders:*", "invoices:read", false},
+        {"orders:*:read", "orders:a:read", true},
+        {"orders:*:read", "orders:a:b:read", false},
+        {"orders:*:read", "orders:a:write", false},
+        {"*:read", "x:read", true},
+        {"*:read", "x:read:y", false},
+        {"*:read", "read", false},
+        {"*", "orders", true},
+        {"*", "orders:a:b", true},
+        {"*", "*", true},
+        {"orders:read", "orders:*", false}, // Wildcards zählen nur auf der gewährten Seite
+        {"", "", false},
+        {"*", "", false},
+        {"", "orders", false},
+        {"Orders:read", "orders:read", false},
+    }
+    for _, c := range cases {
+        if got := scopeMatches(c.granted, c.required); got != c.want {
+            t.Errorf("scopeMatches(%q, %q) = %v, want %v", c.granted, c.required, got, c.want)
+        }
+    }
+}
+
+func TestScopesSet(t *testing.T) {
+    s := Scopes{"orders:*", "profile:read"}
+    if !s.HasAll("orders:write", "profile:read") || s.HasAll("orders:write", "profile:write") {
+        t.Error("HasAll")
+    }
+    if !s.HasAny("profile:write", "orders:x") || s.HasAny("profile:write", "billing") {
+        t.Error("HasAny")
+    }
+    if got := s.Missing("orders:read", "profile:write", "billing"); !reflect.DeepEqual(got, []string{"profile:write", "billing"}) {
+        t.Errorf("Missing: %q", got)
+    }
+    if Scopes(nil).HasScope("orders") || !Scopes(nil).HasAll() {
+        t.Error("empty scopes")
+    }
+}
@@ -1,9 +1,10 @@
 package tokenizer
 
 import (
-	"bytes"
-	"encoding/binary"
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/thelaumix/go-torken/basex"
@@ -13,7 +14,12 @@ import (
 const cIDENTIFIER_LENGTH = 12
 
 // TOKENIZER_LATEST_VERSION analog zum #define
-const cTOKENIZER_LATEST_VERSION = 0x01
+//
+// Version 1 is the legacy format shared with the C library.
+// Version 2 appends a real GCM tag and a random IV salt to TALGO_AES payloads and adds TALGO_XCHACHA20.
+// Version 3 stores a random salt behind the nonce, which is mixed into the cipher nonce.
+// Version 4 adds a flags byte and replaces the checksum with a keyed MAC of configurable length.
+const cTOKENIZER_LATEST_VERSION = 0x04
 
 // encryptOptions dient als Pendant zu C++-encryptOptions. Alles optional per *.
 type encryptOptions struct {
@@ -23,19 +29,45 @@ type encryptOptions struct {
     algorithm  TAlgorithm
     version    uint8
     alphabet   *string
+    tagLength  *int
+    kdf        *KDF
+    keyID      *KeyID // gesetzt von EncryptRing
+    origin     *uint32 // gesetzt von Refresh
+    singleUse  bool
+    purpose    string
+    aad        []byte
+    fingerprint *[]byte
 }
-func (o *encryptOptions) ValidFrom(v time.Time) { 
+func (o *encryptOptions) ValidFrom(v time.Time) *encryptOptions {
     ts := uint32(v.Unix())
-    o.validFrom = &ts 
+    o.validFrom = &ts
+    return o
 }
 func (o *encryptOptions) ValidFromTS(v uint32)   *encryptOptions { o.validFrom = &v; return o }
-func (o *encryptOptions) ExpiresIn(v uint32)     *encryptOptions { o.validFrom = &v; return o }
+// Lifetime in seconds, 0 never expires. Older versions of this setter wrote
+// validFrom instead, see the compatibility notes in the README.
+func (o *encryptOptions) ExpiresIn(v uint32)     *encryptOptions { o.expiresIn = &v; return o }
 func (o *encryptOptions) Scrambler(v string)     *encryptOptions { o.scrambler = &v; return o }
 func (o *encryptOptions) Algorithm(v TAlgorithm) *encryptOptions { o.algorithm = v; return o }
 func (o *encryptOptions) ChaCha20()              *encryptOptions { o.algorithm = TALGO_CHACHA20; return o }
 func (o *encryptOptions) AES()                   *encryptOptions { o.algorithm = TALGO_AES; return o }
+func (o *encryptOptions) XChaCha20()             *encryptOptions { o.algorithm = TALGO_XCHACHA20; return o }
 func (o *encryptOptions) Version(v uint8)        *encryptOptions { o.version = v;   return o }
 func (o *encryptOptions) Alphabet(v string)      *encryptOptions { o.alphabet = &v;  return o }
+// MAC tag length in bytes (version >= 4), multiple of 4 between 8 and 32. Default 16.
+func (o *encryptOptions) TagLength(v int)        *encryptOptions { o.tagLength = &v; return o }
+// Derive the cipher key from the given key (version >= 4). The settings are recorded in the token header.
+func (o *encryptOptions) KDF(v KDF)              *encryptOptions { o.kdf = &v; return o }
+// Token can be decrypted only once (version >= 4). Needs an expiry and a ReplayCache on the decrypting tokenizer.
+func (o *encryptOptions) SingleUse()             *encryptOptions { o.singleUse = true; return o }
+// Bind the token to a purpose (e.g. "session", "email-verify"). It has to be given again on decryption.
+func (o *encryptOptions) Purpose(v string)       *encryptOptions { o.purpose = v; return o }
+// Bind the token to data not stored in it (tenant id, request path, ...). The same data has to be given on decryption.
+func (o *encryptOptions) AssociatedData(v []byte) *encryptOptions { o.aad = v; return o }
+// Bind the token to a client fingerprint (e.g. user agent and IP prefix, TLS certificate hash).
+// The binding is recorded in the header, verifiers reject the token without the same fingerprint.
+// An empty or nil fingerprint fails the encryption instead of issuing an unbound token.
+func (o *encryptOptions) Fingerprint(v []byte)   *encryptOptions { o.fingerprint = &v; return o }
 
 func EncryptOptions() *encryptOptions {
     return &encryptOptions{
@@ -46,11 +78,45 @@ func EncryptOptions() *encryptOptions {
 
 // decryptOptions analog
 type decryptOptions struct {
-    scrambler *string
-    alphabet  *string
+    scrambler  *string
+    alphabet   *string
+    kdf        *KDF
+    validateAt *time.Time
+    leeway     time.Duration
+    strict     *bool
+    issuer     *string
+    audience   *string
+    scopes     []string
+    purpose    string
+    aad        []byte
+    fingerprint []byte
 }
 func (o *decryptOptions) Scrambler(v string)     *decryptOptions { o.scrambler = &v; return o }
 func (o *decryptOptions) Alphabet(v string)      *decryptOptions { o.alphabet = &v;  return o }
+// Require the token to use exactly this key derivation. Without it, HKDF is taken
+// from the token header, scrypt and Argon2id only if allowed with AllowKDF.
+func (o *decryptOptions) KDF(v KDF)              *decryptOptions { o.kdf = &v; return o }
+// Check the validity period at this time instead of the tokenizer clock
+func (o *decryptOptions) ValidateAt(v time.Time)  *decryptOptions { o.validateAt = &v; return o }
+// Accept tokens up to v before validFrom and v after expiry, for clock skew between servers
+func (o *decryptOptions) Leeway(v time.Duration)  *decryptOptions { o.leeway = v; return o }
+// Fail with ErrExpired / ErrNotYetValid before decrypting instead of only flagging the result.
+// Overrides the tokenizer setting (see TokenizerOptions().Strict).
+func (o *decryptOptions) Strict(v bool)           *decryptOptions { o.strict = &v; return o }
+// Require the StandardClaims issuer to be v, see StandardClaimer
+func (o *decryptOptions) ExpectIssuer(v string)   *decryptOptions { o.issuer = &v; return o }
+// Require v to be one of the StandardClaims audiences, see StandardClaimer
+func (o *decryptOptions) ExpectAudience(v string) *decryptOptions { o.audience = &v; return o }
+// Require all of the scopes to be granted by the StandardClaims, see Scopes
+func (o *decryptOptions) RequireScopes(v ...string) *decryptOptions { o.scopes = append(o.scopes, v...); return o }
+// Purpose the token has been encrypted for. Tokens of another purpose fail with ErrIntegrity.
+func (o *decryptOptions) Purpose(v string)       *decryptOptions { o.purpose = v; return o }
+// Associated data the token has been encrypted with. Other data fails with ErrIntegrity.
+func (o *decryptOptions) AssociatedData(v []byte) *decryptOptions { o.aad = v; return o }
+// Fingerprint of the client presenting the token. Ignored for tokens that are not bound.
+// A bound token opened with another fingerprint fails with ErrFingerprintMismatch (and
+// ErrIntegrity, a tampered bound token can not be told apart from one of another client).
+func (o *decryptOptions) Fingerprint(v []byte)   *decryptOptions { o.fingerprint = v; return o }
 
 func DecryptOptions() *decryptOptions {
     return &decryptOptions{}
@@ -59,16 +125,45 @@ func DecryptOptions() *decryptOptions {
 // decryptIntermediate entspricht dem struct in C++
 type decryptIntermediate struct {
     Vhead           byte
+    Flags           byte
     Identifier      []byte
-    Nonce           []byte
+    Checksum        []byte
+    KDF             KDF
+    KeyID           *KeyID
+    Origin          uint32 // nur mit hfORIGIN
+    Salt            []byte
     EncryptedPayload []byte
     ValidFrom       uint32
     ExpiresIn       uint32
     IsValid         bool
+    ValidityErr     error // ErrExpired / ErrNotYetValid, nil if IsValid
+    ValidatedAt     time.Time
+    Leeway          time.Duration
+    config          *tokenizerConfig // Snapshot aus int_inspect
+    options         *decryptOptions
+    AAD             []byte // associated data, nicht im Token gespeichert
+    Fingerprint     []byte // Digest, nur mit hfFINGERPRINT
     // hier könnte man wie im C++-Code I.type usw. abbilden
     PayloadType     byte
 }
 
+// Gültigkeit zum Zeitpunkt now, expiresIn == 0 => kein Ablauf.
+// Leeway erweitert das Fenster auf beiden Seiten.
+func (d *decryptIntermediate) validity(now time.Time, leeway time.Duration) error {
+    if d.ExpiresIn == 0 {
+        return nil
+    }
+    ts := now.Unix()
+    skew := int64(leeway / time.Second)
+    if ts < int64(d.ValidFrom)-skew {
+        return tkErr(STAGE_VALIDATE, ErrNotYetValid)
+    }
+    if ts >= int64(d.ValidFrom)+int64(d.ExpiresIn)+skew {
+        return tkErr(STAGE_VALIDATE, ErrExpired)
+    }
+    return nil
+}
+
 // Methode zum Herauslesen, ob Identifiert benutzt wird
 func (d *decryptIntermediate) UsesIdentifier() bool {
     // vhead 7. Bit
@@ -85,41 +180,171 @@ func (d *decryptIntermediate) Algorithm() TAlgorithm {
     return TAlgorithm((d.Vhead >> 4) & 0x07)
 }
 
-// Tokenizer entspricht der C++-Klasse
-type Tokenizer struct {
+// Einstellungen eines Tokenizers. Werden nie verändert, nur als Ganzes ersetzt,
+// und pro Aufruf genau einmal geladen.
+type tokenizerConfig struct {
     scramblerKey string
     baseX        *basex.BaseX // das BaseX-Gerüst aus dem vorherigen Beispiel
+    clock        Clock
+    strict       bool
+    revoker      Revoker
+    epochs       EpochStore
+    replays      ReplayCache
+    kdfs         []KDF // erlaubte Passphrase-KDFs, siehe AllowKDF
+    kdfSalt      []byte
+    maxLeeway    time.Duration
+}
+
+func (c *tokenizerConfig) now() time.Time {
+    return c.clock.Now()
+}
+
+// Tokenizer entspricht der C++-Klasse.
+// Safe for concurrent use: configure it once with NewTokenizerWith and share it.
+type Tokenizer struct {
+    config    atomic.Pointer[tokenizerConfig]
+    mu        sync.Mutex // serialisiert die (deprecated) Setter
+    kdfCache  *kdfCache
+    alphabets *alphabetCache
+}
+
+// Tokenizer construction options
+type tokenizerOptions struct {
+    scrambler *string
+    alphabet  *string
+    clock     Clock
+    strict    bool
+    revoker   Revoker
+    epochs    EpochStore
+    replays   ReplayCache
+    kdfs      []KDF
+    kdfSalt   []byte
+    maxLeeway time.Duration
+}
+func (o *tokenizerOptions) Scrambler(v string)     *tokenizerOptions { o.scrambler = &v; return o }
+func (o *tokenizerOptions) Alphabet(v string)      *tokenizerOptions { o.alphabet = &v;  return o }
+// Clock used for validFrom defaults and validity checks. Default: system clock.
+func (o *tokenizerOptions) Clock(v Clock)          *tokenizerOptions { o.clock = v;      return o }
+// Reject tokens outside their validity period on every decrypt, unless the
+// decrypt options say otherwise. Off by default, so IsValid has to be checked.
+func (o *tokenizerOptions) Strict(v bool)          *tokenizerOptions { o.strict = v;     return o }
+// Reject revoked identifiers on every decrypt
+func (o *tokenizerOptions) Revoker(v Revoker)      *tokenizerOptions { o.revoker = v;    return o }
+// Reject tokens issued before the epoch of their identifier on every decrypt
+func (o *tokenizerOptions) EpochStore(v EpochStore) *tokenizerOptions { o.epochs = v;    return o }
+// Record single-use tokens on decrypt. Without it, single-use tokens are rejected.
+func (o *tokenizerOptions) ReplayCache(v ReplayCache) *tokenizerOptions { o.replays = v;  return o }
+// Accept tokens using one of these scrypt / Argon2id settings without pinning
+// them in the decrypt options. See KDF.
+func (o *tokenizerOptions) AllowKDF(v ...KDF)      *tokenizerOptions { o.kdfs = append(o.kdfs, v...); return o }
+// Salt for the key derivations of this deployment, so one precomputed dictionary does not
+// work against every torken user. Issuer and verifiers need the same salt. Default: a fixed salt.
+func (o *tokenizerOptions) KDFSalt(v []byte)       *tokenizerOptions { o.kdfSalt = v;    return o }
+// Largest leeway accepted for single-use tokens. Replay entries are kept until
+// expiry plus this leeway, so a larger per-call leeway can not redeem a token
+// again after its entry is gone. Default: 0.
+func (o *tokenizerOptions) MaxLeeway(v time.Duration) *tokenizerOptions { o.maxLeeway = v; return o }
+
+func TokenizerOptions() *tokenizerOptions {
+    return &tokenizerOptions{}
 }
 
 // NewTokenizer als Konstruktor-Ersatz
 func NewTokenizer() *Tokenizer {
-    return &Tokenizer{
+    t, _ := NewTokenizerWith(nil)
+    return t
+}
+
+// New tokenizer with the given options. The settings can not be changed afterwards.
+func NewTokenizerWith(options *tokenizerOptions) (*Tokenizer, error) {
+    c := &tokenizerConfig{
         scramblerKey: "DEFAULT_SCRAMBLER", // entspricht DEFAULT_SCRAMBLER
         baseX:        basex.NewBaseXDefault(),  // dein BaseX-Default-Konstruktor
+        clock:        SystemClock(),
+    }
+    if options != nil {
+        if options.scrambler != nil {
+            c.scramblerKey = *options.scrambler
+        }
+        if options.alphabet != nil {
+            bx, err := basex.NewBaseX(*options.alphabet)
+            if err != nil {
+                return nil, fmt.Errorf("invalid alphabet: %w", err)
+            }
+            c.baseX = bx
+        }
+        if options.clock != nil {
+            c.clock = options.clock
+        }
+        c.strict = options.strict
+        c.revoker = options.revoker
+        c.epochs = options.epochs
+        c.replays = options.replays
+        c.kdfs = append([]KDF(nil), options.kdfs...)
+        c.kdfSalt = append([]byte(nil), options.kdfSalt...)
+        c.maxLeeway = options.maxLeeway
     }
+
+    t := &Tokenizer{
+        kdfCache:  newKDFCache(),
+        alphabets: newAlphabetCache(),
+    }
+    t.config.Store(c)
+    return t, nil
+}
+
+// Copy-on-write für die Setter, laufende Aufrufe behalten ihren Snapshot
+func (t *Tokenizer) update(fn func(c *tokenizerConfig) error) error {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    next := *t.config.Load()
+    if err := fn(&next); err != nil {
+        return err
+    }
+    t.config.Store(&next)
+    return nil
 }
 
 // SetAlphabet analog zur C++-Methode
+//
+// Deprecated: use TokenizerOptions().Alphabet with NewTokenizerWith.
 func (t *Tokenizer) SetAlphabet(alphabet string) error {
-    if len(alphabet) < 2 {
-        return errors.New("invalid alphabet length")
+    bx, err := basex.NewBaseX(alphabet)
+    if err != nil {
+        return fmt.Errorf("invalid alphabet: %w", err)
     }
-    return t.baseX.SetAlphabet(alphabet)
+    return t.update(func(c *tokenizerConfig) error {
+        c.baseX = bx
+        return nil
+    })
 }
 
 // SetScrambler analog
+//
+// Deprecated: use TokenizerOptions().Scrambler with NewTokenizerWith.
 func (t *Tokenizer) SetScrambler(scrambler string) {
-    t.scramblerKey = scrambler
+    t.update(func(c *tokenizerConfig) error {
+        c.scramblerKey = scrambler
+        return nil
+    })
 }
 
 // GetScrambler analog
 func (t *Tokenizer) GetScrambler() string {
-    return t.scramblerKey
+    return t.config.Load().scramblerKey
 }
 
 // GetAlphabet analog
 func (t *Tokenizer) GetAlphabet() string {
-    return t.baseX.GetAlphabet()
+    return t.config.Load().baseX.GetAlphabet()
+}
+
+// Transcoder for a call: the tokenizer's own, or a cached one for a per-call alphabet
+func (t *Tokenizer) transcoder(c *tokenizerConfig, alphabet *string) (*basex.BaseX, error) {
+    if alphabet == nil || *alphabet == c.baseX.GetAlphabet() {
+        return c.baseX, nil
+    }
+    return t.alphabets.get(*alphabet)
 }
 
 // int_encrypt grob nach dem Vorbild des C++-Codes.
@@ -132,13 +357,24 @@ func (t *Tokenizer) int_encrypt(identifier []byte, key string, payload []byte,
     options *encryptOptions) (string, error) {
 
     data := append([]byte(nil), payload...) // Payload kopieren
+    c := t.config.Load()
 
-    validFrom := uint32(time.Now().Unix())
+    validFrom := uint32(c.now().Unix())
     expiresIn := uint32(0)
     algorithm := TALGO_CHACHA20
     version := uint8(cTOKENIZER_LATEST_VERSION)
     useIdentifier := identifier != nil
-    scrambkey := t.scramblerKey
+    scrambkey := c.scramblerKey
+    var alphabet *string
+    tagLength := cDEFAULT_TAG_LENGTH
+    tagLengthSet := false
+    kdf := KDF{}
+    var keyID *KeyID
+    var origin *uint32
+    singleUse := false
+    purpose := ""
+    var aad []byte
+    var fingerprint *[]byte
 
     // Falls options != nil, Felder ggf. überschreiben
     if options != nil {
@@ -153,9 +389,31 @@ func (t *Tokenizer) int_encrypt(identifier []byte, key string, payload []byte,
             scrambkey = *options.scrambler
         }
         version = options.version
-        if options.alphabet != nil {
-            _ = t.baseX.SetAlphabet(*options.alphabet)
+        if options.tagLength != nil {
+            tagLength = *options.tagLength
+            tagLengthSet = true
+        }
+        if options.kdf != nil {
+            kdf = *options.kdf
         }
+        keyID = options.keyID
+        origin = options.origin
+        singleUse = options.singleUse
+        purpose = options.purpose
+        aad = options.aad
+        fingerprint = options.fingerprint
+        alphabet = options.alphabet
+    }
+
+    bx, err := t.transcoder(c, alphabet)
+    if err != nil {
+        return "", tkErr(STAGE_ENCRYPT, err)
+    }
+    if version < 1 || version > cTOKENIZER_LATEST_VERSION {
+        return "", tkErr(STAGE_ENCRYPT, fmt.Errorf("%w %d", ErrUnsupportedVersion, version))
+    }
+    if !algorithmSupported(algorithm, version) {
+        return "", tkErr(STAGE_ENCRYPT, fmt.Errorf("%w %d for version %d", ErrUnsupportedAlgorithm, algorithm, version))
     }
 
     // Vhead zusammenbauen
@@ -166,105 +424,168 @@ func (t *Tokenizer) int_encrypt(identifier []byte, key string, payload []byte,
         vhead |= 1 << 7
     }
 
-    // Checksum Input (vhead + identifier + validFrom + expiresIn + payload)
-    var checksumInput bytes.Buffer
-    _ = checksumInput.WriteByte(vhead)
-    if useIdentifier {
-        checksumInput.Write(identifier)
+    I := &decryptIntermediate{
+        Vhead:      vhead,
+        Identifier: identifier,
+        ValidFrom:  validFrom,
+        ExpiresIn:  expiresIn,
+        AAD:        aad,
     }
-    binary.Write(&checksumInput, binary.LittleEndian, validFrom)
-    binary.Write(&checksumInput, binary.LittleEndian, expiresIn)
-    checksumInput.Write(data)
 
-    checksum := make([]byte, 8)
-    makeChecksum(checksum, checksumInput.Bytes()) // -> externes Hilfsfunktion
+    // Header-Flags (ab Version 4)
+    if version >= 4 {
+        code, err := tagLengthCode(tagLength)
+        if err != nil {
+            return "", tkErr(STAGE_ENCRYPT, err)
+        }
+        I.Flags |= code
 
-    // Nonce = validFrom + expiresIn + checksum (jeweils 4,4,8)
-    nonce := make([]byte, 16)
-    binary.LittleEndian.PutUint32(nonce[0:], validFrom)
-    binary.LittleEndian.PutUint32(nonce[4:], expiresIn)
-    copy(nonce[8:], checksum)
+        if kdf.Algorithm != KDF_NONE {
+            I.Flags |= hfKDF
+            I.KDF = kdf
+        }
+        if keyID != nil {
+            I.Flags |= hfKEY_ID
+            I.KeyID = keyID
+        }
+        if origin != nil {
+            I.Flags |= hfORIGIN
+            I.Origin = *origin
+        }
+        if singleUse {
+            if expiresIn == 0 {
+                return "", tkErr(STAGE_ENCRYPT, errors.New("single-use tokens need an expiry"))
+            }
+            I.Flags |= hfSINGLE_USE
+        }
+        if fingerprint != nil {
+            if len(*fingerprint) == 0 {
+                return "", tkErr(STAGE_ENCRYPT, wrapErr(ErrFingerprintMismatch, "no client fingerprint to bind the token to"))
+            }
+            I.Flags |= hfFINGERPRINT
+            I.Fingerprint = fingerprintDigest(*fingerprint)
+        }
+    } else if kdf.Algorithm != KDF_NONE {
+        return "", tkErr(STAGE_ENCRYPT, wrapErr(ErrUnsupportedVersion, "key derivation requires token version 4 or later"))
+    } else if keyID != nil {
+        return "", tkErr(STAGE_ENCRYPT, wrapErr(ErrUnsupportedVersion, "key ids require token version 4 or later"))
+    } else if origin != nil {
+        return "", tkErr(STAGE_ENCRYPT, wrapErr(ErrUnsupportedVersion, "refreshed tokens require token version 4 or later"))
+    } else if singleUse {
+        return "", tkErr(STAGE_ENCRYPT, wrapErr(ErrUnsupportedVersion, "single-use tokens require token version 4 or later"))
+    } else if fingerprint != nil {
+        return "", tkErr(STAGE_ENCRYPT, wrapErr(ErrUnsupportedVersion, "fingerprint binding requires token version 4 or later"))
+    } else if tagLengthSet {
+        return "", tkErr(STAGE_ENCRYPT, wrapErr(ErrUnsupportedVersion, "tag lengths require token version 4 or later"))
+    }
 
-    // Verschlüsseln
-    encrypted, err := crpEncrypt(algorithm, data, key, nonce)
-    //err := crpEncrypt(algorithm, encrypted, data, key, nonce)
+    key, err = t.kdfCache.derive(key, kdf, c.kdfSalt)
     if err != nil {
-        return "", err
+        return "", tkErr(STAGE_KEY, err)
     }
+    key = purposeKey(key, purpose)
 
-    // finalBuffer = [vhead][identifier][nonce][encrypted]
-    var finalBuffer bytes.Buffer
-    finalBuffer.WriteByte(vhead)
-    if useIdentifier {
-        finalBuffer.Write(identifier)
+    // Salt bzw. XChaCha20-Nonce, danach Checksum/MAC über den Klartext
+    if err := I.randomizeSalt(); err != nil {
+        return "", tkErr(STAGE_ENCRYPT, err)
     }
-    finalBuffer.Write(nonce)
-    finalBuffer.Write(encrypted)
+    I.Checksum = I.integrity(key, data)
+
+    // Verschlüsseln
+    encrypted, err := I.seal(key, data)
+    if err != nil {
+        return "", tkErr(STAGE_ENCRYPT, err)
+    }
+    I.EncryptedPayload = encrypted
+
+    // finalBuffer = [vhead][flags][identifier][validFrom][expiresIn][checksum][salt][encrypted]
+    finalBuffer := I.bytes()
 
     // PseudoShuffle
-    PseudoShuffle(finalBuffer.Bytes(), scrambkey) // Stub-Funktion
+    PseudoShuffle(finalBuffer, scrambkey) // Stub-Funktion
 
     // BaseX-encode
-    outResult, err := t.baseX.Encode(finalBuffer.Bytes())
+    outResult, err := bx.Encode(finalBuffer)
     if err != nil {
-        return "", err
+        return "", tkErr(STAGE_ENCRYPT, err)
     }
     return outResult, nil
 }
 
 // int_decrypt_begin entspricht Decrypt_Begin
 func (t *Tokenizer) int_decrypt_begin(tokenString string, options *decryptOptions) (*decryptIntermediate, error) {
-    scrambkey := t.scramblerKey
+    c := t.config.Load()
+    I, err := t.int_inspect(c, tokenString, options)
+    if err != nil {
+        return nil, err
+    }
+
+    // strict: gar nicht erst entschlüsseln
+    strict := c.strict
+    if options != nil && options.strict != nil {
+        strict = *options.strict
+    }
+    if strict && !I.IsValid {
+        return nil, I.ValidityErr
+    }
+    return I, nil
+}
+
+// Header lesen und Gültigkeit bestimmen, ohne Schlüssel und ohne Strict-Prüfung
+func (t *Tokenizer) int_inspect(c *tokenizerConfig, tokenString string, options *decryptOptions) (*decryptIntermediate, error) {
+    scrambkey := c.scramblerKey
+    var alphabet *string
 
     if options != nil {
         if options.scrambler != nil {
             scrambkey = *options.scrambler
         }
-        if options.alphabet != nil {
-            _ = t.baseX.SetAlphabet(*options.alphabet)
-        }
+        alphabet = options.alphabet
     }
 
-    encryptedData, err := t.baseX.Decode(tokenString)
+    bx, err := t.transcoder(c, alphabet)
     if err != nil {
-        return nil, err
+        return nil, tkErr(STAGE_DECODE, err)
+    }
+    encryptedData, err := bx.Decode(tokenString)
+    if err != nil {
+        if !errors.Is(err, ErrInvalidAlphabetChar) {
+            err = wrapErr(ErrMalformed, err.Error())
+        }
+        return nil, tkErr(STAGE_DECODE, err)
     }
 
     // Unshuffle
     PseudoUnshuffle(encryptedData, scrambkey)
 
-    if len(encryptedData) < 1 {
-        return nil, errors.New("corrupt data: too short")
+    I, err := parseIntermediate(encryptedData)
+    if err != nil {
+        return nil, tkErr(STAGE_PARSE, err)
     }
 
-    I := &decryptIntermediate{}
-    I.Vhead = encryptedData[0]
-
-    usesIdentifier := I.UsesIdentifier()
-    expectedIDSize := 0
-    if usesIdentifier {
-        expectedIDSize = cIDENTIFIER_LENGTH
+    if options != nil && options.kdf != nil && *options.kdf != I.KDF {
+        return nil, tkErr(STAGE_PARSE, wrapErr(ErrKeyDerivation, "token does not use the expected key derivation"))
     }
 
-    minLen := 1 + expectedIDSize + 16
-    if len(encryptedData) < minLen {
-        return nil, errors.New("corrupt data: length too small")
+    I.config = c
+    I.options = options
+    if options != nil {
+        I.AAD = options.aad
+        if I.Flags&hfFINGERPRINT != 0 && len(options.fingerprint) > 0 {
+            I.Fingerprint = fingerprintDigest(options.fingerprint)
+        }
     }
-
-    // identifier
-    I.Identifier = append([]byte(nil), encryptedData[1:1+expectedIDSize]...)
-    // nonce
-    nonceStart := 1 + expectedIDSize
-    I.Nonce = append([]byte(nil), encryptedData[nonceStart:nonceStart+16]...)
-    // rest = encryptedPayload
-    I.EncryptedPayload = append([]byte(nil), encryptedData[nonceStart+16:]...)
-
-    I.ValidFrom = binary.LittleEndian.Uint32(I.Nonce[0:4])
-    I.ExpiresIn = binary.LittleEndian.Uint32(I.Nonce[4:8])
-
-    now := uint32(time.Now().Unix())
-    // expiresIn == 0 => kein Ablauf
-    I.IsValid = (I.ExpiresIn == 0 || (now >= I.ValidFrom && now < I.ValidFrom+I.ExpiresIn))
+    I.ValidatedAt = c.now()
+    leeway := time.Duration(0)
+    if options != nil {
+        if options.validateAt != nil {
+            I.ValidatedAt = *options.validateAt
+        }
+        leeway = options.leeway
+    }
+    I.Leeway = leeway
+    I.ValidityErr = I.validity(I.ValidatedAt, leeway)
+    I.IsValid = I.ValidityErr == nil
 
     return I, nil
 }
@@ -272,29 +593,40 @@ func (t *Tokenizer) int_decrypt_begin(tokenString string, options *decryptOption
 // int_decrypt_finalize entspricht Decrypt_Finalize
 // Man übergibt das Intermediate und bekommt die entschlüsselte Payload zurück
 func (t *Tokenizer) int_decrypt_finalize(I *decryptIntermediate, key string) ([]byte, error) {
+    if err := I.checkFingerprint(); err != nil {
+        return nil, tkErr(STAGE_VERIFY, err)
+    }
+    // vor dem Ableiten, der Header ist noch nicht geprüft
+    if err := I.checkKDF(); err != nil {
+        return nil, tkErr(STAGE_KEY, err)
+    }
 
-    decrypted, err := crpDecrypt(I.Algorithm(), I.EncryptedPayload, key, I.Nonce)
+    key, err := t.kdfCache.derive(key, I.KDF, I.config.kdfSalt)
     if err != nil {
-        return nil, err
+        return nil, tkErr(STAGE_KEY, err)
+    }
+    if I.options != nil {
+        key = purposeKey(key, I.options.purpose)
     }
 
-    // Checksum prüfen
-    var checkBuf bytes.Buffer
-    checkBuf.WriteByte(I.Vhead)
-    if I.UsesIdentifier() {
-        checkBuf.Write(I.Identifier)
+    decrypted, err := I.open(key)
+    if err != nil {
+        return nil, tkErr(STAGE_DECRYPT, I.fingerprintErr(err))
     }
-    binary.Write(&checkBuf, binary.LittleEndian, I.ValidFrom)
-    binary.Write(&checkBuf, binary.LittleEndian, I.ExpiresIn)
-    checkBuf.Write(decrypted)
 
-    computed := make([]byte, 8)
-    makeChecksum(computed, checkBuf.Bytes())
+    // Checksum bzw. MAC prüfen (constant-time)
+    if !I.verifyIntegrity(key, decrypted) {
+        return nil, tkErr(STAGE_VERIFY, I.fingerprintErr(wrapErr(ErrIntegrity, "checksum mismatch")))
+    }
 
-    // I.Nonce[8..16] == checksum
-    if !bytes.Equal(computed, I.Nonce[8:16]) {
-        return nil, errors.New("token integrity invalid")
+    // erst nach der Prüfung des MAC, sonst ließe sich die Liste mit gefälschten Headern abfragen
+    if err := I.checkRevoked(); err != nil {
+        return nil, tkErr(STAGE_CHECK, err)
+    }
+    if err := I.checkEpoch(); err != nil {
+        return nil, tkErr(STAGE_CHECK, err)
     }
+    // checkReplay erst in Into, nach der Claims-Prüfung
 
     // „Type“ = decryptedPayload[0], analog I.type = ...
     if len(decrypted) > 0 {
@@ -0,0 +1,108 @@
+package tokenizer
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Decryption result: validity, identifier and header details of a token
+type Result = tkResult
+
+// Encrypt v. A nil identifier makes the token anonymous.
+func Seal[T any](t *Tokenizer, v T, key string, identifier *Identifier, options *encryptOptions) (string, error) {
+    return t.Encrypt(v, key, identifier, options)
+}
+
+// Decrypt a token into a value of type T
+func Open[T any](t *Tokenizer, token, key string, options *decryptOptions) (T, *Result, error) {
+    var v T
+    r, err := t.Decrypt(token, key, options).Into(&v)
+    if err != nil {
+        var zero T
+        return zero, nil, err
+    }
+    return v, r, nil
+}
+
+// Declaration of a kind of token with payload type T and its defaults.
+// Configure it once where it is declared, it must not be changed while in use.
+//
+//   var ResetToken = tokenizer.NewTokenKind[ResetPayload]().TTL(time.Hour).XChaCha20()
+//
+//   tok, err := ResetToken.Seal(t, payload, key, id)
+//   payload, res, err := ResetToken.Open(t, tok, key)
+type TokenKind[T any] struct {
+    ttl       time.Duration
+    algorithm TAlgorithm
+    scrambler *string
+    purpose   string
+    strict    bool
+}
+
+// New token kind. Tokens of a kind are opened strictly: expired or not yet valid
+// tokens fail with ErrExpired / ErrNotYetValid, see Strict.
+func NewTokenKind[T any]() *TokenKind[T] {
+    return &TokenKind[T]{algorithm: TALGO_CHACHA20, strict: true}
+}
+
+// Lifetime of new tokens, 0 for tokens that never expire. Rounded up to whole
+// seconds, so a sub-second TTL does not turn into "never expires".
+// Panics for negative values and values above math.MaxUint32 seconds.
+func (k *TokenKind[T]) TTL(v time.Duration)       *TokenKind[T] {
+    if v < 0 || ttlSeconds(v) > math.MaxUint32 {
+        panic(fmt.Sprintf("torken: TokenKind TTL %v out of range", v))
+    }
+    k.ttl = v
+    return k
+}
+func (k *TokenKind[T]) Algorithm(v TAlgorithm)    *TokenKind[T] { k.algorithm = v;  return k }
+func (k *TokenKind[T]) ChaCha20()                 *TokenKind[T] { k.algorithm = TALGO_CHACHA20;  return k }
+func (k *TokenKind[T]) AES()                      *TokenKind[T] { k.algorithm = TALGO_AES;       return k }
+func (k *TokenKind[T]) XChaCha20()                *TokenKind[T] { k.algorithm = TALGO_XCHACHA20; return k }
+func (k *TokenKind[T]) Scrambler(v string)        *TokenKind[T] { k.scrambler = &v; return k }
+// Bind tokens of this kind to a purpose, so they can not be opened as another kind with the same key
+func (k *TokenKind[T]) Purpose(v string)          *TokenKind[T] { k.purpose = v;    return k }
+// Fail on tokens outside their validity period when opening. On by default; with
+// false, Open returns them and only Result.IsValid() tells.
+func (k *TokenKind[T]) Strict(v bool)             *TokenKind[T] { k.strict = v;     return k }
+
+// Whole seconds, rounded up
+func ttlSeconds(v time.Duration) int64 {
+    secs := int64(v / time.Second)
+    if v%time.Second != 0 {
+        secs++
+    }
+    return secs
+}
+
+// Fresh encrypt options with the defaults of this kind, to be adjusted per call
+func (k *TokenKind[T]) EncryptOptions() *encryptOptions {
+    o := EncryptOptions().Algorithm(k.algorithm).Purpose(k.purpose)
+    if k.ttl > 0 {
+        o.ExpiresIn(uint32(ttlSeconds(k.ttl)))
+    }
+    if k.scrambler != nil {
+        o.Scrambler(*k.scrambler)
+    }
+    return o
+}
+
+// Fresh decrypt options with the defaults of this kind
+func (k *TokenKind[T]) DecryptOptions() *decryptOptions {
+    o := DecryptOptions().Purpose(k.purpose).Strict(k.strict)
+    if k.scrambler != nil {
+        o.Scrambler(*k.scrambler)
+    }
+    return o
+}
+
+// Encrypt v with the defaults of this kind. A nil identifier makes the token anonymous.
+func (k *TokenKind[T]) Seal(t *Tokenizer, v T, key string, identifier *Identifier) (string, error) {
+    return Seal(t, v, key, identifier, k.EncryptOptions())
+}
+
+// Decrypt a token of this kind
+func (k *TokenKind[T]) Open(t *Tokenizer, token, key string) (T, *Result, error) {
+    return Open[T](t, token, key, k.DecryptOptions())
+}
@@ -0,0 +1,65 @@
+package tokenizer
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTokenKindTTL(t *testing.T) {
+    cases := []struct {
+        ttl  time.Duration
+        want uint32
+    }{
+        {500 * time.Millisecond, 1},
+        {time.Second, 1},
+        {1500 * time.Millisecond, 2},
+        {time.Hour, 3600},
+    }
+    for _, tc := range cases {
+        o := NewTokenKind[string]().TTL(tc.ttl).EncryptOptions()
+        if o.expiresIn == nil || *o.expiresIn != tc.want {
+            t.Errorf("TTL %v: expiresIn %v, want %d", tc.ttl, o.expiresIn, tc.want)
+        }
+    }
+    if o := NewTokenKind[string]().EncryptOptions(); o.expiresIn != nil {
+        t.Errorf("no TTL: expiresIn %d, want unset", *o.expiresIn)
+    }
+}
+
+func TestTokenKindTTLOutOfRange(t *testing.T) {
+    for _, ttl := range []time.Duration{-time.Second, time.Duration(math.MaxUint32+1) * time.Second, math.MaxInt64} {
+        func() {
+            defer func() {
+                if recover() == nil {
+                    t.Errorf("TTL %v: no panic", ttl)
+                }
+            }()
+            NewTokenKind[string]().TTL(ttl)
+        }()
+    }
+}
+
+// Kinds open strictly unless told otherwise
+func TestTokenKindStrict(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    tk := clockTokenizer(t, &now)
+    reset := NewTokenKind[string]().TTL(time.Hour).Purpose("reset")
+    token, err := reset.Seal(tk, "alice", "key", NewIdentifier())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if v, _, err := reset.Open(tk, token, "key"); err != nil || v != "alice" {
+        t.Fatalf("valid: %q, %v", v, err)
+    }
+
+    now = now.Add(2 * time.Hour)
+    if _, _, err := reset.Open(tk, token, "key"); !errors.Is(err, ErrExpired) {
+        t.Errorf("expired: got %v, want ErrExpired", err)
+    }
+    lenient := NewTokenKind[string]().TTL(time.Hour).Purpose("reset").Strict(false)
+    if v, res, err := lenient.Open(tk, token, "key"); err != nil || v != "alice" || res.IsValid() {
+        t.Errorf("lenient: %q, %v, valid %v", v, err, res != nil && res.IsValid())
+    }
+}
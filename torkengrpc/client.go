@@ -0,0 +1,50 @@
+package torkengrpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Token for an outgoing call. An empty token sends the call without one.
+type TokenSource func(ctx context.Context) (string, error)
+
+// Always the same token
+func StaticToken(token string) TokenSource {
+    return func(context.Context) (string, error) { return token, nil }
+}
+
+// Attach the token as "authorization: Bearer <token>"
+func withToken(ctx context.Context, source TokenSource) (context.Context, error) {
+    token, err := source(ctx)
+    if err != nil {
+        return ctx, err
+    }
+    if len(token) == 0 {
+        return ctx, nil
+    }
+    return metadata.AppendToOutgoingContext(ctx, DefaultMetadataKey, "Bearer "+token), nil
+}
+
+// Unary client interceptor attaching the token of source to every call
+func UnaryClientInterceptor(source TokenSource) grpc.UnaryClientInterceptor {
+    return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+        ctx, err := withToken(ctx, source)
+        if err != nil {
+            return err
+        }
+        return invoker(ctx, method, req, reply, cc, opts...)
+    }
+}
+
+// Stream client interceptor attaching the token of source to every stream
+func StreamClientInterceptor(source TokenSource) grpc.StreamClientInterceptor {
+    return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+        ctx, err := withToken(ctx, source)
+        if err != nil {
+            return nil, err
+        }
+        return streamer(ctx, desc, cc, method, opts...)
+    }
+}
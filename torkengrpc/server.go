@@ -0,0 +1,200 @@
+// Package torkengrpc authenticates gRPC calls with torken tokens.
+//
+//   srv := grpc.NewServer(
+//       grpc.UnaryInterceptor(torkengrpc.UnaryServerInterceptor[Session](tk, torkengrpc.Options().StaticKey(secret))),
+//       grpc.StreamInterceptor(torkengrpc.StreamServerInterceptor[Session](tk, torkengrpc.Options().StaticKey(secret))),
+//   )
+//
+//   // im Handler
+//   session, ok := torkengrpc.Claims[Session](ctx)
+package torkengrpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/thelaumix/go-torken/internal/authcore"
+	"github.com/thelaumix/go-torken/tokenizer"
+)
+
+// Default metadata key, carrying "Bearer <token>"
+const DefaultMetadataKey = "authorization"
+
+// Returned when the incoming metadata carries no token
+var ErrNoToken = errors.New("no token in metadata")
+
+// Key for the identifier of a token
+type KeyResolver func(ctx context.Context, id tokenizer.Identifier) (string, error)
+
+// Converts an authentication error into the error returned to the client
+type ErrorHandler func(ctx context.Context, fullMethod string, err error) error
+
+type options struct {
+    core        authcore.Options[context.Context]
+    metadataKey string
+    skip        func(fullMethod string) bool
+    onError     ErrorHandler
+}
+// Metadata key to read the token from. Default: "authorization" with a Bearer prefix.
+func (o *options) MetadataKey(v string)        *options { o.metadataKey = strings.ToLower(v); return o }
+// Resolve the key per token identifier
+func (o *options) KeyResolver(v KeyResolver)   *options { o.core.Resolver = v; o.core.Ring = nil; return o }
+// Use the same key for all tokens
+func (o *options) StaticKey(key string)        *options {
+    return o.KeyResolver(func(context.Context, tokenizer.Identifier) (string, error) { return key, nil })
+}
+// Pick the key by the key id in the token header
+func (o *options) KeyRing(v *tokenizer.KeyRing) *options { o.core.Ring = v; o.core.Resolver = nil; return o }
+func (o *options) Scrambler(v string)          *options { o.core.Scrambler = &v; return o }
+func (o *options) Alphabet(v string)           *options { o.core.Alphabet = &v; return o }
+// Clock skew tolerance for the validity check
+func (o *options) Leeway(v time.Duration)      *options { o.core.Leeway = v; return o }
+// Let expired tokens through; the handler has to check Result().IsValid(). Off by default.
+func (o *options) Lenient(v bool)              *options { o.core.Lenient = v; return o }
+// Key derivation the tokens use. Needed for scrypt / Argon2id tokens unless allowed on the tokenizer.
+func (o *options) KDF(v tokenizer.KDF)         *options { o.core.KDF = &v; return o }
+// Reject tokens whose StandardClaims do not grant all of the scopes
+func (o *options) RequireScopes(v ...string)   *options { o.core.Scopes = append(o.core.Scopes, v...); return o }
+// Purpose the tokens have been encrypted for
+func (o *options) Purpose(v string)            *options { o.core.Purpose = v; return o }
+// Associated data per call the tokens have been bound to. grpc.Method(ctx) gives the called method.
+func (o *options) AssociatedData(v func(ctx context.Context) []byte) *options { o.core.AAD = v; return o }
+// Client fingerprint per call (e.g. from peer.FromContext) for tokens bound to one
+func (o *options) Fingerprint(v func(ctx context.Context) []byte) *options { o.core.Fingerprint = v; return o }
+// Methods that need no token, e.g. health checks. Gets the full method name.
+func (o *options) Skip(v func(fullMethod string) bool) *options { o.skip = v; return o }
+// Custom error conversion. Default: DefaultErrorHandler.
+func (o *options) OnError(v ErrorHandler)      *options { o.onError = v; return o }
+
+func Options() *options {
+    return &options{}
+}
+
+// Claims stored by the interceptor, false if there are none of type T
+func Claims[T any](ctx context.Context) (T, bool) {
+    return authcore.Claims[T](ctx)
+}
+
+// Decryption result (identifier, validity, header details) stored by the interceptor
+func Result(ctx context.Context) (*tokenizer.Result, bool) {
+    return authcore.Result(ctx)
+}
+
+// gRPC status for an authentication error:
+//   - missing or rejected tokens: Unauthenticated
+//   - valid tokens lacking a required scope: PermissionDenied
+//   - everything else (e.g. a failing key resolver): Internal
+func CodeFor(err error) codes.Code {
+    switch authcore.Classify(err, ErrNoToken) {
+    case authcore.CLASS_FORBIDDEN:
+        return codes.PermissionDenied
+    case authcore.CLASS_UNAUTHENTICATED:
+        return codes.Unauthenticated
+    }
+    return codes.Internal
+}
+
+// Status error with the code from CodeFor. The error itself is not exposed to the client.
+func DefaultErrorHandler(ctx context.Context, fullMethod string, err error) error {
+    code := CodeFor(err)
+    if code == codes.Unauthenticated {
+        return status.Error(code, "unauthenticated")
+    }
+    if code == codes.PermissionDenied {
+        return status.Error(code, "permission denied")
+    }
+    return status.Error(code, "authentication failed")
+}
+
+func prepare(options *options) *options {
+    if options == nil || !options.core.HasKey() {
+        panic("torkengrpc: no key resolver or key ring configured")
+    }
+    o := *options
+    if len(o.metadataKey) == 0 {
+        o.metadataKey = DefaultMetadataKey
+    }
+    if o.onError == nil {
+        o.onError = DefaultErrorHandler
+    }
+    return &o
+}
+
+// Unary server interceptor decrypting the call token into claims of type T.
+// Panics if neither a key resolver nor a key ring is configured.
+func UnaryServerInterceptor[T any](t *tokenizer.Tokenizer, options *options) grpc.UnaryServerInterceptor {
+    o := prepare(options)
+    return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+        if o.skip != nil && o.skip(info.FullMethod) {
+            return handler(ctx, req)
+        }
+        ctx, err := authenticate[T](ctx, t, o)
+        if err != nil {
+            return nil, o.onError(ctx, info.FullMethod, err)
+        }
+        return handler(ctx, req)
+    }
+}
+
+// Stream server interceptor decrypting the call token into claims of type T.
+// Panics if neither a key resolver nor a key ring is configured.
+func StreamServerInterceptor[T any](t *tokenizer.Tokenizer, options *options) grpc.StreamServerInterceptor {
+    o := prepare(options)
+    return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+        if o.skip != nil && o.skip(info.FullMethod) {
+            return handler(srv, ss)
+        }
+        ctx, err := authenticate[T](ss.Context(), t, o)
+        if err != nil {
+            return o.onError(ss.Context(), info.FullMethod, err)
+        }
+        return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+    }
+}
+
+// ServerStream mit ersetztem Context
+type authStream struct {
+    grpc.ServerStream
+    ctx context.Context
+}
+
+func (s *authStream) Context() context.Context {
+    return s.ctx
+}
+
+func (o *options) extract(ctx context.Context) (string, bool) {
+    md, ok := metadata.FromIncomingContext(ctx)
+    if !ok {
+        return "", false
+    }
+    for _, v := range md.Get(o.metadataKey) {
+        token := strings.TrimSpace(v)
+        if scheme, rest, found := strings.Cut(token, " "); found && strings.EqualFold(scheme, "Bearer") {
+            token = strings.TrimSpace(rest)
+        }
+        if len(token) > 0 {
+            return token, true
+        }
+    }
+    return "", false
+}
+
+func authenticate[T any](ctx context.Context, t *tokenizer.Tokenizer, o *options) (context.Context, error) {
+    token, found := o.extract(ctx)
+    if !found {
+        return ctx, ErrNoToken
+    }
+
+    claims, res, err := authcore.Authenticate[T](t, &o.core, ctx, token)
+    if err != nil {
+        return ctx, err
+    }
+    return authcore.WithClaims(ctx, claims, res), nil
+}
@@ -0,0 +1,228 @@
+package torkengrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/thelaumix/go-torken/tokenizer"
+)
+
+const (
+    tUNARY_METHOD  = "/torken.test.Whoami/Unary"
+    tSTREAM_METHOD = "/torken.test.Whoami/Stream"
+)
+
+// Subject der Claims im Context, "-" ohne
+func whoami(ctx context.Context) string {
+    claims, ok := Claims[tokenizer.StandardClaims](ctx)
+    if !ok {
+        return "-"
+    }
+    return claims.Subject
+}
+
+var whoamiService = grpc.ServiceDesc{
+    ServiceName: "torken.test.Whoami",
+    HandlerType: (*any)(nil),
+    Methods: []grpc.MethodDesc{{
+        MethodName: "Unary",
+        Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+            in := new(wrapperspb.StringValue)
+            if err := dec(in); err != nil {
+                return nil, err
+            }
+            handler := func(ctx context.Context, req any) (any, error) { return wrapperspb.String(whoami(ctx)), nil }
+            if interceptor == nil {
+                return handler(ctx, in)
+            }
+            return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: tUNARY_METHOD}, handler)
+        },
+    }},
+    Streams: []grpc.StreamDesc{{
+        StreamName:    "Stream",
+        ServerStreams: true,
+        Handler: func(srv any, stream grpc.ServerStream) error {
+            in := new(wrapperspb.StringValue)
+            if err := stream.RecvMsg(in); err != nil {
+                return err
+            }
+            return stream.SendMsg(wrapperspb.String(whoami(stream.Context())))
+        },
+    }},
+}
+
+// Server mit den Interceptoren über bufconn, Client mit den Optionen
+func dial(t *testing.T, tk *tokenizer.Tokenizer, options *options, dialOpts ...grpc.DialOption) *grpc.ClientConn {
+    t.Helper()
+    lis := bufconn.Listen(1 << 16)
+    srv := grpc.NewServer(
+        grpc.UnaryInterceptor(UnaryServerInterceptor[tokenizer.StandardClaims](tk, options)),
+        grpc.StreamInterceptor(StreamServerInterceptor[tokenizer.StandardClaims](tk, options)),
+    )
+    srv.RegisterService(&whoamiService, struct{}{})
+    go srv.Serve(lis)
+    t.Cleanup(srv.Stop)
+
+    dialOpts = append(dialOpts,
+        grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+        grpc.WithTransportCredentials(insecure.NewCredentials()))
+    conn, err := grpc.NewClient("passthrough:///bufnet", dialOpts...)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    return conn
+}
+
+func callUnary(ctx context.Context, conn *grpc.ClientConn) (string, error) {
+    out := new(wrapperspb.StringValue)
+    err := conn.Invoke(ctx, tUNARY_METHOD, wrapperspb.String("hi"), out)
+    return out.GetValue(), err
+}
+
+func callStream(ctx context.Context, conn *grpc.ClientConn) (string, error) {
+    stream, err := conn.NewStream(ctx, &whoamiService.Streams[0], tSTREAM_METHOD)
+    if err != nil {
+        return "", err
+    }
+    if err := stream.SendMsg(wrapperspb.String("hi")); err != nil {
+        return "", err
+    }
+    if err := stream.CloseSend(); err != nil {
+        return "", err
+    }
+    out := new(wrapperspb.StringValue)
+    err = stream.RecvMsg(out)
+    return out.GetValue(), err
+}
+
+func withBearer(token string) context.Context {
+    return metadata.AppendToOutgoingContext(context.Background(), DefaultMetadataKey, "Bearer "+token)
+}
+
+func issue(t *testing.T, tk *tokenizer.Tokenizer, claims tokenizer.StandardClaims) string {
+    t.Helper()
+    token, err := tk.Encrypt(claims, "secret", nil, tokenizer.EncryptOptions().ExpiresIn(60))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return token
+}
+
+func TestServerInterceptors(t *testing.T) {
+    tk := tokenizer.NewTokenizer()
+    conn := dial(t, tk, Options().StaticKey("secret"))
+    token := issue(t, tk, tokenizer.StandardClaims{Subject: "alice"})
+    tampered := token[:len(token)/2] + string(token[len(token)/2]^1) + token[len(token)/2+1:]
+
+    for name, call := range map[string]func(context.Context, *grpc.ClientConn) (string, error){"unary": callUnary, "stream": callStream} {
+        if sub, err := call(withBearer(token), conn); err != nil || sub != "alice" {
+            t.Errorf("%s: got %q, %v", name, sub, err)
+        }
+        if _, err := call(context.Background(), conn); status.Code(err) != codes.Unauthenticated {
+            t.Errorf("%s without token: got %v, want Unauthenticated", name, err)
+        }
+        _, err := call(withBearer(tampered), conn)
+        if status.Code(err) != codes.Unauthenticated {
+            t.Errorf("%s tampered: got %v, want Unauthenticated", name, err)
+        }
+        // der Fehler selbst wird nicht an den Client gegeben
+        if msg := status.Convert(err).Message(); msg != "unauthenticated" {
+            t.Errorf("%s tampered: message %q exposed", name, msg)
+        }
+    }
+}
+
+func TestServerInterceptorsSkip(t *testing.T) {
+    tk := tokenizer.NewTokenizer()
+    conn := dial(t, tk, Options().StaticKey("secret").Skip(func(m string) bool { return m == tUNARY_METHOD }))
+
+    if sub, err := callUnary(context.Background(), conn); err != nil || sub != "-" {
+        t.Errorf("skipped unary: got %q, %v", sub, err)
+    }
+    if _, err := callStream(context.Background(), conn); status.Code(err) != codes.Unauthenticated {
+        t.Errorf("stream: got %v, want Unauthenticated", err)
+    }
+}
+
+func TestServerInterceptorsCodes(t *testing.T) {
+    tk := tokenizer.NewTokenizer()
+    token := issue(t, tk, tokenizer.StandardClaims{Subject: "alice", Scope: tokenizer.Scopes{"read"}})
+
+    scoped := dial(t, tk, Options().StaticKey("secret").RequireScopes("write"))
+    if _, err := callUnary(withBearer(token), scoped); status.Code(err) != codes.PermissionDenied {
+        t.Errorf("missing scope: got %v, want PermissionDenied", err)
+    }
+    if _, err := callStream(withBearer(token), scoped); status.Code(err) != codes.PermissionDenied {
+        t.Errorf("stream missing scope: got %v, want PermissionDenied", err)
+    }
+
+    failing := dial(t, tk, Options().KeyResolver(func(context.Context, tokenizer.Identifier) (string, error) {
+        return "", errors.New("key store down")
+    }))
+    if _, err := callUnary(withBearer(token), failing); status.Code(err) != codes.Internal {
+        t.Errorf("resolver error: got %v, want Internal", err)
+    }
+}
+
+func TestCodeFor(t *testing.T) {
+    cases := []struct {
+        err  error
+        want codes.Code
+    }{
+        {ErrNoToken, codes.Unauthenticated},
+        {tokenizer.ErrUnknownKey, codes.Unauthenticated},
+        {tokenizer.ErrKeyRetired, codes.Unauthenticated},
+        {&tokenizer.TokenError{Stage: tokenizer.STAGE_VERIFY, Err: tokenizer.ErrIntegrity}, codes.Unauthenticated},
+        {&tokenizer.TokenError{Stage: tokenizer.STAGE_CLAIMS, Err: tokenizer.ErrInsufficientScope}, codes.PermissionDenied},
+        {errors.New("key store down"), codes.Internal},
+    }
+    for _, c := range cases {
+        if got := CodeFor(c.err); got != c.want {
+            t.Errorf("CodeFor(%v) = %v, want %v", c.err, got, c.want)
+        }
+    }
+}
+
+func TestClientInterceptors(t *testing.T) {
+    tk := tokenizer.NewTokenizer()
+    token := issue(t, tk, tokenizer.StandardClaims{Subject: "bob"})
+    conn := dial(t, tk, Options().StaticKey("secret"),
+        grpc.WithUnaryInterceptor(UnaryClientInterceptor(StaticToken(token))),
+        grpc.WithStreamInterceptor(StreamClientInterceptor(StaticToken(token))))
+
+    if sub, err := callUnary(context.Background(), conn); err != nil || sub != "bob" {
+        t.Errorf("unary: got %q, %v", sub, err)
+    }
+    if sub, err := callStream(context.Background(), conn); err != nil || sub != "bob" {
+        t.Errorf("stream: got %q, %v", sub, err)
+    }
+
+    ctx, err := withToken(context.Background(), StaticToken(token))
+    if md, _ := metadata.FromOutgoingContext(ctx); err != nil || len(md.Get("authorization")) != 1 || md.Get("authorization")[0] != "Bearer "+token {
+        t.Errorf("metadata: got %v, %v", md, err)
+    }
+
+    // leerer Token: Aufruf ohne authorization
+    empty := dial(t, tk, Options().StaticKey("secret"), grpc.WithUnaryInterceptor(UnaryClientInterceptor(StaticToken(""))))
+    if _, err := callUnary(context.Background(), empty); status.Code(err) != codes.Unauthenticated {
+        t.Errorf("empty token: got %v, want Unauthenticated", err)
+    }
+
+    failing := dial(t, tk, Options().StaticKey("secret"), grpc.WithUnaryInterceptor(UnaryClientInterceptor(func(context.Context) (string, error) {
+        return "", errors.New("no session")
+    })))
+    if _, err := callUnary(context.Background(), failing); err == nil || err.Error() != "no session" {
+        t.Errorf("failing source: got %v", err)
+    }
+}
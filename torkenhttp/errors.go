@@ -0,0 +1,31 @@
+package torkenhttp
+
+import (
+	"net/http"
+
+	"github.com/thelaumix/go-torken/internal/authcore"
+)
+
+// HTTP status for an authentication error:
+//   - missing or rejected tokens: 401
+//   - valid tokens lacking a required scope: 403
+//   - everything else (e.g. a failing key resolver): 500
+func StatusFor(err error) int {
+    switch authcore.Classify(err, ErrNoToken) {
+    case authcore.CLASS_FORBIDDEN:
+        return http.StatusForbidden
+    case authcore.CLASS_UNAUTHENTICATED:
+        return http.StatusUnauthorized
+    }
+    return http.StatusInternalServerError
+}
+
+// Writes the status from StatusFor with its status text. The error itself is
+// not exposed to the client.
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+    status := StatusFor(err)
+    if status == http.StatusUnauthorized {
+        w.Header().Set("WWW-Authenticate", `Bearer`)
+    }
+    http.Error(w, http.StatusText(status), status)
+}
@@ -0,0 +1,49 @@
+package torkenhttp
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Pulls the raw token out of a request
+type Extractor func(r *http.Request) (token string, ok bool)
+
+// Token from the `Authorization: Bearer <token>` header
+func FromBearer() Extractor {
+    return func(r *http.Request) (string, bool) {
+        auth := r.Header.Get("Authorization")
+        scheme, token, found := strings.Cut(auth, " ")
+        if !found || !strings.EqualFold(scheme, "Bearer") {
+            return "", false
+        }
+        token = strings.TrimSpace(token)
+        return token, len(token) > 0
+    }
+}
+
+// Token from a cookie
+func FromCookie(name string) Extractor {
+    return func(r *http.Request) (string, bool) {
+        c, err := r.Cookie(name)
+        if err != nil || len(c.Value) == 0 {
+            return "", false
+        }
+        return c.Value, true
+    }
+}
+
+// Token from a custom request header
+func FromHeader(name string) Extractor {
+    return func(r *http.Request) (string, bool) {
+        token := strings.TrimSpace(r.Header.Get(name))
+        return token, len(token) > 0
+    }
+}
+
+// Token from a query parameter, e.g. for links in emails
+func FromQuery(name string) Extractor {
+    return func(r *http.Request) (string, bool) {
+        token := r.URL.Query().Get(name)
+        return token, len(token) > 0
+    }
+}
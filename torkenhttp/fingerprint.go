@@ -0,0 +1,80 @@
+package torkenhttp
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/netip"
+)
+
+// Derives the client fingerprint of a request, nil if there is none. The same
+// function has to be used when issuing the token:
+//
+//   tokenizer.EncryptOptions().Fingerprint(fp(r))
+//
+// Issuing fails with ErrFingerprintMismatch if fp(r) is nil, so a missing
+// fingerprint never quietly produces an unbound token.
+type Fingerprinter func(r *http.Request) []byte
+
+// User agent plus the client IP cut to a prefix (e.g. 24 / 64 bits), so clients
+// keep their tokens when the address changes within their network. Uses
+// r.RemoteAddr; behind a proxy write a Fingerprinter reading the trusted header.
+func UserAgentIP(v4Bits, v6Bits int) Fingerprinter {
+    return func(r *http.Request) []byte {
+        host, _, err := net.SplitHostPort(r.RemoteAddr)
+        if err != nil {
+            host = r.RemoteAddr
+        }
+        addr, err := netip.ParseAddr(host)
+        if err != nil {
+            return nil
+        }
+        addr = addr.Unmap()
+        bits := v6Bits
+        if addr.Is4() {
+            bits = v4Bits
+        }
+        prefix, err := addr.Prefix(bits)
+        if err != nil {
+            return nil
+        }
+        return joinParts([]byte(r.UserAgent()), []byte(prefix.String()))
+    }
+}
+
+// SHA256 of the TLS client certificate, nil without one
+func ClientCertificate() Fingerprinter {
+    return func(r *http.Request) []byte {
+        if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+            return nil
+        }
+        sum := sha256.Sum256(r.TLS.PeerCertificates[0].Raw)
+        return sum[:]
+    }
+}
+
+// All fingerprints together, nil if any of them is missing
+func Combine(fps ...Fingerprinter) Fingerprinter {
+    return func(r *http.Request) []byte {
+        parts := make([][]byte, 0, len(fps))
+        for _, fp := range fps {
+            part := fp(r)
+            if part == nil {
+                return nil
+            }
+            parts = append(parts, part)
+        }
+        return joinParts(parts...)
+    }
+}
+
+// Length prefixed, so parts can not be shifted into each other
+func joinParts(parts ...[]byte) []byte {
+    var out []byte
+    for _, p := range parts {
+        out = binary.LittleEndian.AppendUint32(out, uint32(len(p)))
+        out = append(out, p...)
+    }
+    return out
+}
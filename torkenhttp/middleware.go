@@ -0,0 +1,152 @@
+// Package torkenhttp authenticates net/http requests with torken tokens.
+//
+//   mw := torkenhttp.Middleware[Session](tk, torkenhttp.Options().StaticKey(secret))
+//   http.Handle("/api/", mw(api))
+//
+//   // im Handler
+//   session, ok := torkenhttp.Claims[Session](r.Context())
+//
+// Tokens bound to a client fingerprint (see Fingerprinter) need the same
+// Fingerprinter in Options().Fingerprint.
+package torkenhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thelaumix/go-torken/internal/authcore"
+	"github.com/thelaumix/go-torken/tokenizer"
+)
+
+// Returned when no extractor found a token in the request
+var ErrNoToken = errors.New("no token in request")
+
+// Key for the identifier of a token. Errors are reported to the error handler.
+type KeyResolver func(r *http.Request, id tokenizer.Identifier) (string, error)
+
+// Writes the response for a request that failed authentication
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+type options struct {
+    core       authcore.Options[*http.Request]
+    extractors []Extractor
+    optional   bool
+    onError    ErrorHandler
+}
+// Where to look for the token, in order. Default: FromBearer.
+func (o *options) Extract(v ...Extractor)      *options { o.extractors = append(o.extractors, v...); return o }
+// Resolve the key per token identifier
+func (o *options) KeyResolver(v KeyResolver)   *options { o.core.Resolver = v; o.core.Ring = nil; return o }
+// Use the same key for all tokens
+func (o *options) StaticKey(key string)        *options {
+    return o.KeyResolver(func(*http.Request, tokenizer.Identifier) (string, error) { return key, nil })
+}
+// Pick the key by the key id in the token header
+func (o *options) KeyRing(v *tokenizer.KeyRing) *options { o.core.Ring = v; o.core.Resolver = nil; return o }
+func (o *options) Scrambler(v string)          *options { o.core.Scrambler = &v; return o }
+func (o *options) Alphabet(v string)           *options { o.core.Alphabet = &v; return o }
+// Clock skew tolerance for the validity check
+func (o *options) Leeway(v time.Duration)      *options { o.core.Leeway = v; return o }
+// Let expired tokens through; the handler has to check Result().IsValid(). Off by default.
+func (o *options) Lenient(v bool)              *options { o.core.Lenient = v; return o }
+// Key derivation the tokens use. Needed for scrypt / Argon2id tokens unless allowed on the tokenizer.
+func (o *options) KDF(v tokenizer.KDF)         *options { o.core.KDF = &v; return o }
+// Reject tokens whose StandardClaims do not grant all of the scopes
+func (o *options) RequireScopes(v ...string)   *options { o.core.Scopes = append(o.core.Scopes, v...); return o }
+// Purpose the tokens have been encrypted for
+func (o *options) Purpose(v string)            *options { o.core.Purpose = v; return o }
+// Associated data per request the tokens have been bound to, e.g. the tenant from the host name
+func (o *options) AssociatedData(v func(r *http.Request) []byte) *options { o.core.AAD = v; return o }
+// Client fingerprint for tokens bound to one. Bound tokens presented by another client are rejected.
+func (o *options) Fingerprint(v Fingerprinter) *options { o.core.Fingerprint = v; return o }
+// Let requests without a token through without claims. Invalid tokens are still rejected.
+func (o *options) Optional(v bool)             *options { o.optional = v; return o }
+// Custom error response. Default: DefaultErrorHandler.
+func (o *options) OnError(v ErrorHandler)      *options { o.onError = v; return o }
+
+func Options() *options {
+    return &options{}
+}
+
+// Claims stored by the middleware, false if the request carried no token
+// or the middleware was declared with another type
+func Claims[T any](ctx context.Context) (T, bool) {
+    return authcore.Claims[T](ctx)
+}
+
+// Decryption result (identifier, validity, header details) stored by the middleware
+func Result(ctx context.Context) (*tokenizer.Result, bool) {
+    return authcore.Result(ctx)
+}
+
+// Middleware decrypting the request token into claims of type T.
+// Panics if neither a key resolver nor a key ring is configured.
+func Middleware[T any](t *tokenizer.Tokenizer, options *options) func(http.Handler) http.Handler {
+    if options == nil || !options.core.HasKey() {
+        panic("torkenhttp: no key resolver or key ring configured")
+    }
+    o := *options
+    if len(o.extractors) == 0 {
+        o.extractors = []Extractor{FromBearer()}
+    }
+    if o.onError == nil {
+        o.onError = DefaultErrorHandler
+    }
+
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            token, found := o.extract(r)
+            if !found {
+                if o.optional {
+                    next.ServeHTTP(w, r)
+                    return
+                }
+                o.onError(w, r, ErrNoToken)
+                return
+            }
+
+            claims, res, err := authcore.Authenticate[T](t, &o.core, r, token)
+            if err != nil {
+                o.onError(w, r, err)
+                return
+            }
+
+            next.ServeHTTP(w, r.WithContext(authcore.WithClaims(r.Context(), claims, res)))
+        })
+    }
+}
+
+func (o *options) extract(r *http.Request) (string, bool) {
+    for _, ex := range o.extractors {
+        if token, ok := ex(r); ok {
+            return token, true
+        }
+    }
+    return "", false
+}
+
+// Handler wrapper rejecting requests whose token does not grant all of the
+// scopes, for routes behind Middleware that need more than the rest. Requests
+// without a token are rejected with ErrNoToken. A nil onError uses DefaultErrorHandler.
+func RequireScopes(onError ErrorHandler, scopes ...string) func(http.Handler) http.Handler {
+    if onError == nil {
+        onError = DefaultErrorHandler
+    }
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            res, ok := Result(r.Context())
+            if !ok {
+                onError(w, r, ErrNoToken)
+                return
+            }
+            if missing := res.Scopes().Missing(scopes...); len(missing) > 0 {
+                onError(w, r, fmt.Errorf("%w: missing %q", tokenizer.ErrInsufficientScope, missing))
+                return
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
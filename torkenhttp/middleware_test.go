@@ -0,0 +1,204 @@
+package torkenhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/thelaumix/go-torken/tokenizer"
+)
+
+// Handler hinter der Middleware: Subject der Claims, "-" ohne, "expired" bei abgelaufenen Tokens
+var whoami = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    claims, ok := Claims[tokenizer.StandardClaims](r.Context())
+    if !ok {
+        w.Write([]byte("-"))
+        return
+    }
+    if res, _ := Result(r.Context()); !res.IsValid() {
+        w.Write([]byte("expired"))
+        return
+    }
+    w.Write([]byte(claims.Subject))
+})
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+    rec := httptest.NewRecorder()
+    h.ServeHTTP(rec, r)
+    return rec
+}
+
+func request(token string) *http.Request {
+    r := httptest.NewRequest(http.MethodGet, "/", nil)
+    if len(token) > 0 {
+        r.Header.Set("Authorization", "Bearer "+token)
+    }
+    return r
+}
+
+// Token mit 60s Laufzeit; fingerprint nil für ungebundene Tokens
+func issue(t *testing.T, tk *tokenizer.Tokenizer, claims tokenizer.StandardClaims, fingerprint []byte) string {
+    t.Helper()
+    eopts := tokenizer.EncryptOptions().ExpiresIn(60)
+    if fingerprint != nil {
+        eopts.Fingerprint(fingerprint)
+    }
+    token, err := tk.Encrypt(claims, "secret", nil, eopts)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return token
+}
+
+func expectResponse(t *testing.T, name string, rec *httptest.ResponseRecorder, status int, body string) {
+    t.Helper()
+    if rec.Code != status {
+        t.Errorf("%s: status %d, want %d", name, rec.Code, status)
+        return
+    }
+    if status == http.StatusOK && rec.Body.String() != body {
+        t.Errorf("%s: body %q, want %q", name, rec.Body.String(), body)
+    }
+}
+
+func TestMiddlewareExtract(t *testing.T) {
+    tk := tokenizer.NewTokenizer()
+    token := issue(t, tk, tokenizer.StandardClaims{Subject: "alice"}, nil)
+
+    bearerOnly := Middleware[tokenizer.StandardClaims](tk, Options().StaticKey("secret"))(whoami)
+    expectResponse(t, "bearer", serve(bearerOnly, request(token)), http.StatusOK, "alice")
+
+    withCookie := httptest.NewRequest(http.MethodGet, "/", nil)
+    withCookie.AddCookie(&http.Cookie{Name: "session", Value: token})
+    expectResponse(t, "cookie, bearer only", serve(bearerOnly, withCookie), http.StatusUnauthorized, "")
+
+    cookie := Middleware[tokenizer.StandardClaims](tk, Options().StaticKey("secret").Extract(FromBearer(), FromCookie("session")))(whoami)
+    expectResponse(t, "cookie", serve(cookie, withCookie), http.StatusOK, "alice")
+    expectResponse(t, "bearer before cookie", serve(cookie, request(token)), http.StatusOK, "alice")
+}
+
+func TestMiddlewareRejects(t *testing.T) {
+    tk := tokenizer.NewTokenizer()
+    mw := Middleware[tokenizer.StandardClaims](tk, Options().StaticKey("secret"))(whoami)
+
+    rec := serve(mw, request(""))
+    expectResponse(t, "missing", rec, http.StatusUnauthorized, "")
+    if got := rec.Header().Get("WWW-Authenticate"); got != "Bearer" {
+        t.Errorf("missing: WWW-Authenticate %q, want Bearer", got)
+    }
+
+    token := issue(t, tk, tokenizer.StandardClaims{Subject: "alice"}, nil)
+    tampered := token[:len(token)/2] + string(token[len(token)/2]^1) + token[len(token)/2+1:]
+    rec = serve(mw, request(tampered))
+    expectResponse(t, "tampered", rec, http.StatusUnauthorized, "")
+    if got := rec.Header().Get("WWW-Authenticate"); got != "Bearer" {
+        t.Errorf("tampered: WWW-Authenticate %q, want Bearer", got)
+    }
+
+    other := Middleware[tokenizer.StandardClaims](tk, Options().StaticKey("other"))(whoami)
+    expectResponse(t, "wrong key", serve(other, request(token)), http.StatusUnauthorized, "")
+}
+
+func TestMiddlewareExpiry(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    tk, err := tokenizer.NewTokenizerWith(tokenizer.TokenizerOptions().Clock(tokenizer.ClockFunc(func() time.Time { return now })))
+    if err != nil {
+        t.Fatal(err)
+    }
+    token := issue(t, tk, tokenizer.StandardClaims{Subject: "alice"}, nil)
+    now = now.Add(2 * time.Minute)
+
+    strict := Middleware[tokenizer.StandardClaims](tk, Options().StaticKey("secret"))(whoami)
+    expectResponse(t, "strict", serve(strict, request(token)), http.StatusUnauthorized, "")
+
+    leeway := Middleware[tokenizer.StandardClaims](tk, Options().StaticKey("secret").Leeway(2*time.Minute))(whoami)
+    expectResponse(t, "leeway", serve(leeway, request(token)), http.StatusOK, "alice")
+
+    lenient := Middleware[tokenizer.StandardClaims](tk, Options().StaticKey("secret").Lenient(true))(whoami)
+    expectResponse(t, "lenient", serve(lenient, request(token)), http.StatusOK, "expired")
+}
+
+func TestMiddlewareScopes(t *testing.T) {
+    tk := tokenizer.NewTokenizer()
+    token := issue(t, tk, tokenizer.StandardClaims{Subject: "alice", Scope: tokenizer.Scopes{"read"}}, nil)
+
+    required := Middleware[tokenizer.StandardClaims](tk, Options().StaticKey("secret").RequireScopes("write"))(whoami)
+    expectResponse(t, "option", serve(required, request(token)), http.StatusForbidden, "")
+
+    mw := Middleware[tokenizer.StandardClaims](tk, Options().StaticKey("secret"))
+    expectResponse(t, "wrapper granted", serve(mw(RequireScopes(nil, "read")(whoami)), request(token)), http.StatusOK, "alice")
+    expectResponse(t, "wrapper missing", serve(mw(RequireScopes(nil, "write")(whoami)), request(token)), http.StatusForbidden, "")
+    // ohne Middleware davor gibt es kein Result
+    expectResponse(t, "wrapper alone", serve(RequireScopes(nil, "read")(whoami), request(token)), http.StatusUnauthorized, "")
+}
+
+func TestMiddlewareResolverError(t *testing.T) {
+    tk := tokenizer.NewTokenizer()
+    token := issue(t, tk, tokenizer.StandardClaims{Subject: "alice"}, nil)
+
+    var got error
+    mw := Middleware[tokenizer.StandardClaims](tk, Options().
+        KeyResolver(func(*http.Request, tokenizer.Identifier) (string, error) { return "", errors.New("key store down") }).
+        OnError(func(w http.ResponseWriter, r *http.Request, err error) {
+            got = err
+            DefaultErrorHandler(w, r, err)
+        }))(whoami)
+
+    rec := serve(mw, request(token))
+    expectResponse(t, "resolver error", rec, http.StatusInternalServerError, "")
+    if got == nil || got.Error() != "key store down" {
+        t.Errorf("resolver error: handler got %v", got)
+    }
+    if rec.Header().Get("WWW-Authenticate") != "" {
+        t.Errorf("resolver error: unexpected WWW-Authenticate")
+    }
+}
+
+func TestMiddlewareOptional(t *testing.T) {
+    tk := tokenizer.NewTokenizer()
+    mw := Middleware[tokenizer.StandardClaims](tk, Options().StaticKey("secret").Optional(true))(whoami)
+
+    expectResponse(t, "no token", serve(mw, request("")), http.StatusOK, "-")
+    token := issue(t, tk, tokenizer.StandardClaims{Subject: "alice"}, nil)
+    expectResponse(t, "token", serve(mw, request(token)), http.StatusOK, "alice")
+    expectResponse(t, "invalid token", serve(mw, request(token[:len(token)-2])), http.StatusUnauthorized, "")
+}
+
+func TestMiddlewareFingerprint(t *testing.T) {
+    tk := tokenizer.NewTokenizer()
+    fp := UserAgentIP(24, 64)
+
+    issuedTo := request("")
+    issuedTo.RemoteAddr = "192.0.2.10:4711"
+    issuedTo.Header.Set("User-Agent", "test/1.0")
+    token := issue(t, tk, tokenizer.StandardClaims{Subject: "alice"}, fp(issuedTo))
+
+    mw := Middleware[tokenizer.StandardClaims](tk, Options().StaticKey("secret").Fingerprint(fp))(whoami)
+
+    // gleiches /24-Netz, gleicher User-Agent
+    same := request(token)
+    same.RemoteAddr = "192.0.2.77:5000"
+    same.Header.Set("User-Agent", "test/1.0")
+    expectResponse(t, "same client", serve(mw, same), http.StatusOK, "alice")
+
+    otherNet := request(token)
+    otherNet.RemoteAddr = "198.51.100.10:4711"
+    otherNet.Header.Set("User-Agent", "test/1.0")
+    expectResponse(t, "other network", serve(mw, otherNet), http.StatusUnauthorized, "")
+
+    otherAgent := request(token)
+    otherAgent.RemoteAddr = "192.0.2.10:4711"
+    otherAgent.Header.Set("User-Agent", "curl/8.0")
+    expectResponse(t, "other user agent", serve(mw, otherAgent), http.StatusUnauthorized, "")
+
+    // ohne Fingerprinter wird ein gebundenes Token nicht angenommen
+    unbound := Middleware[tokenizer.StandardClaims](tk, Options().StaticKey("secret"))(whoami)
+    expectResponse(t, "no fingerprinter", serve(unbound, same), http.StatusUnauthorized, "")
+
+    // ohne Client-Zertifikat lässt sich kein gebundenes Token ausstellen
+    if _, err := tk.Encrypt(tokenizer.StandardClaims{}, "secret", nil, tokenizer.EncryptOptions().Fingerprint(ClientCertificate()(same))); !errors.Is(err, tokenizer.ErrFingerprintMismatch) {
+        t.Errorf("issue without certificate: got %v, want ErrFingerprintMismatch", err)
+    }
+}